@@ -0,0 +1,338 @@
+package acs
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+// DefaultConnectionRequestTimeout is how long a connection request may take
+// if HTTPConnectionRequester.Timeout is zero.
+const DefaultConnectionRequestTimeout = 10 * time.Second
+
+var (
+	ErrAuthFailed  = errors.New("acs: Connection request authentication failed")
+	ErrUnreachable = errors.New("acs: Device unreachable")
+	ErrTimeout     = errors.New("acs: Connection request timed out")
+	ErrNoURL       = errors.New("acs: No connection request URL")
+)
+
+var errUnsupportedAlgorithm = errors.New("acs: Unsupported digest algorithm")
+
+// HTTPConnectionRequester sends connection requests to the
+// ConnectionRequestURL that devices report in their Informs.
+type HTTPConnectionRequester struct {
+	// Store is where the Informs are recorded, the Store of the Server.
+	Store Store
+
+	// Credentials returns the ConnectionRequestUsername and
+	// ConnectionRequestPassword the ACS configured on the device.
+	Credentials func(device cwmp.Identity) (username, password string, err error)
+
+	Client  *http.Client
+	Timeout time.Duration
+}
+
+// informAddress returns the address field picks from the last Inform of
+// device recorded in store, or ErrNoURL if there is none.
+func informAddress(store Store, device cwmp.Identity, field func(s *cwmp.DeviceSummary) string) (string, error) {
+	d, err := store.Device(device)
+	if err == ErrNotFound || err == nil && field(&d.Summary) == "" {
+		return "", ErrNoURL
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return field(&d.Summary), nil
+}
+
+// deviceCredentials returns the credentials of device from credentials, or
+// none if it is nil.
+func deviceCredentials(credentials func(device cwmp.Identity) (string, string, error), device cwmp.Identity) (string, string, error) {
+	if credentials == nil {
+		return "", "", nil
+	}
+
+	return credentials(device)
+}
+
+func (c *HTTPConnectionRequester) ConnectionRequest(ctx context.Context, device cwmp.Identity) error {
+	u, err := informAddress(c.Store, device, func(s *cwmp.DeviceSummary) string {
+		return s.ConnectionRequestURL
+	})
+	if err != nil {
+		return err
+	}
+
+	username, password, err := deviceCredentials(c.Credentials, device)
+	if err != nil {
+		return err
+	}
+
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultConnectionRequestTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return ConnectionRequest(ctx, c.Client, u, username, password)
+}
+
+// ConnectionRequest sends an HTTP connection request to rawurl, answering a
+// Digest or Basic challenge with username and password. It returns nil if
+// the device accepted it, or an error wrapping ErrAuthFailed, ErrUnreachable
+// or ErrTimeout. Any other answer than a success or an authentication
+// failure counts as unreachable. If client is nil http.DefaultClient is used.
+func ConnectionRequest(ctx context.Context, client *http.Client, rawurl, username, password string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := connectionRequest(ctx, client, rawurl, "")
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		auth, err := authorization(resp.Header["Www-Authenticate"], resp.Request.URL, username, password)
+		if err != nil {
+			return err
+		}
+
+		resp, err = connectionRequest(ctx, client, rawurl, auth)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuthFailed
+	}
+
+	return fmt.Errorf("%w: Connection request to (%s): %s", ErrUnreachable, rawurl, resp.Status)
+}
+
+func connectionRequest(ctx context.Context, client *http.Client, rawurl, auth string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.WithContext(ctx)
+
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var nerr net.Error
+
+		if ctx.Err() == context.DeadlineExceeded || errors.As(err, &nerr) && nerr.Timeout() {
+			return nil, fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		return nil, fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+
+	return resp, nil
+}
+
+// parseChallenge splits a WWW-Authenticate header into its scheme and
+// parameters.
+func parseChallenge(h string) (string, map[string]string) {
+	h = strings.TrimSpace(h)
+
+	i := strings.IndexByte(h, ' ')
+	if i < 0 {
+		return strings.ToLower(h), nil
+	}
+
+	scheme := strings.ToLower(h[:i])
+	params := make(map[string]string)
+
+	s := h[i+1:]
+
+	for {
+		s = strings.TrimLeft(s, " ,")
+		if s == "" {
+			break
+		}
+
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimLeft(s[eq+1:], " ")
+
+		var value string
+
+		if strings.HasPrefix(s, `"`) {
+			var b strings.Builder
+
+			j := 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+				}
+
+				b.WriteByte(s[j])
+			}
+
+			if j < len(s) {
+				j++
+			}
+
+			value = b.String()
+			s = s[j:]
+		} else {
+			j := strings.IndexByte(s, ',')
+			if j < 0 {
+				j = len(s)
+			}
+
+			value = strings.TrimSpace(s[:j])
+			s = s[j:]
+		}
+
+		params[key] = value
+	}
+
+	return scheme, params
+}
+
+// authorization answers the first Digest challenge with a supported
+// algorithm, or else a Basic challenge.
+func authorization(challenges []string, u *url.URL, username, password string) (string, error) {
+	var basic bool
+
+	for _, c := range challenges {
+		scheme, params := parseChallenge(c)
+
+		switch scheme {
+		case "digest":
+			auth, err := digestAuthorization(params, u, username, password)
+			if errors.Is(err, errUnsupportedAlgorithm) {
+				continue
+			}
+
+			return auth, err
+		case "basic":
+			basic = true
+		}
+	}
+
+	if !basic {
+		return "", ErrAuthFailed
+	}
+
+	req := &http.Request{Header: http.Header{}}
+	req.SetBasicAuth(username, password)
+
+	return req.Header.Get("Authorization"), nil
+}
+
+func digestAuthorization(params map[string]string, u *url.URL, username, password string) (string, error) {
+	var h func() hash.Hash
+
+	algorithm := params["algorithm"]
+
+	switch strings.ToUpper(algorithm) {
+	case "", "MD5", "MD5-SESS":
+		h = md5.New
+	case "SHA-256", "SHA-256-SESS":
+		h = sha256.New
+	default:
+		return "", fmt.Errorf("%w (%s)", errUnsupportedAlgorithm, algorithm)
+	}
+
+	digest := func(s ...string) string {
+		d := h()
+		io.WriteString(d, strings.Join(s, ":"))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	realm, nonce := params["realm"], params["nonce"]
+	uri := u.RequestURI()
+
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	cnonce := hex.EncodeToString(b)
+	nc := "00000001"
+
+	ha1 := digest(username, realm, password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = digest(ha1, nonce, cnonce)
+	}
+
+	ha2 := digest(http.MethodGet, uri)
+
+	var qop string
+	for _, q := range strings.Split(params["qop"], ",") {
+		if strings.TrimSpace(q) == "auth" {
+			qop = "auth"
+		}
+	}
+
+	fields := []string{
+		fmt.Sprintf("username=%q", username),
+		fmt.Sprintf("realm=%q", realm),
+		fmt.Sprintf("nonce=%q", nonce),
+		fmt.Sprintf("uri=%q", uri),
+	}
+
+	if qop != "" {
+		fields = append(fields,
+			"qop="+qop,
+			"nc="+nc,
+			fmt.Sprintf("cnonce=%q", cnonce),
+			fmt.Sprintf("response=%q", digest(ha1, nonce, nc, cnonce, qop, ha2)),
+		)
+	} else {
+		fields = append(fields, fmt.Sprintf("response=%q", digest(ha1, nonce, ha2)))
+	}
+
+	if algorithm != "" {
+		fields = append(fields, "algorithm="+algorithm)
+	}
+
+	if opaque, ok := params["opaque"]; ok {
+		fields = append(fields, fmt.Sprintf("opaque=%q", opaque))
+	}
+
+	return "Digest " + strings.Join(fields, ", "), nil
+}
@@ -0,0 +1,222 @@
+package acs
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+func md5Hex(s ...string) string {
+	sum := md5.Sum([]byte(strings.Join(s, ":")))
+	return hex.EncodeToString(sum[:])
+}
+
+// digestCPE is a stand-in CPE that accepts connection requests authenticated
+// with Digest as username and password.
+func digestCPE(qop string) *httptest.Server {
+	const nonce = "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, params := parseChallenge(r.Header.Get("Authorization"))
+		if scheme != "digest" {
+			challenge := `Digest realm="cpe", nonce="` + nonce + `", opaque="5ccc"`
+			if qop != "" {
+				challenge += `, qop="` + qop + `"`
+			}
+
+			w.Header().Set("WWW-Authenticate", challenge)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		ha1 := md5Hex("admin", "cpe", "secret")
+		ha2 := md5Hex("GET", r.URL.RequestURI())
+
+		want := md5Hex(ha1, nonce, ha2)
+		if qop != "" {
+			want = md5Hex(ha1, nonce, params["nc"], params["cnonce"], params["qop"], ha2)
+		}
+
+		if params["username"] != "admin" || params["uri"] != r.URL.RequestURI() || params["opaque"] != "5ccc" || params["response"] != want {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Digest realm="a \"b\", c", qop="auth,auth-int", nonce=abc, stale=FALSE`)
+
+	want := map[string]string{
+		"realm": `a "b", c`,
+		"qop":   "auth,auth-int",
+		"nonce": "abc",
+		"stale": "FALSE",
+	}
+
+	if scheme != "digest" || !reflect.DeepEqual(params, want) {
+		t.Fatalf("Expected (%v) got (%s) (%v)", want, scheme, params)
+	}
+}
+
+func TestConnectionRequestDigest(t *testing.T) {
+	for _, qop := range []string{"auth", "auth,auth-int", ""} {
+		srv := digestCPE(qop)
+
+		err := ConnectionRequest(context.Background(), nil, srv.URL+"/cr?x=1", "admin", "secret")
+		if err != nil {
+			t.Fatalf("qop (%s) err: %v", qop, err)
+		}
+
+		err = ConnectionRequest(context.Background(), nil, srv.URL+"/cr", "admin", "wrong")
+		if err != ErrAuthFailed {
+			t.Fatalf("Expected (%v) got (%v)", ErrAuthFailed, err)
+		}
+
+		srv.Close()
+	}
+}
+
+func TestConnectionRequestBasic(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="cpe"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}))
+	defer srv.Close()
+
+	err := ConnectionRequest(context.Background(), nil, srv.URL, "admin", "secret")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = ConnectionRequest(context.Background(), nil, srv.URL, "admin", "wrong")
+	if err != ErrAuthFailed {
+		t.Fatalf("Expected (%v) got (%v)", ErrAuthFailed, err)
+	}
+}
+
+func TestConnectionRequestUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	err := ConnectionRequest(context.Background(), nil, url, "", "")
+	if !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("Expected (%v) got (%v)", ErrUnreachable, err)
+	}
+}
+
+func TestConnectionRequestTimeout(t *testing.T) {
+	done := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := ConnectionRequest(ctx, nil, srv.URL, "", "")
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Expected (%v) got (%v)", ErrTimeout, err)
+	}
+}
+
+func TestHTTPConnectionRequester(t *testing.T) {
+	srv := digestCPE("auth")
+	defer srv.Close()
+
+	store := &MemoryStore{}
+
+	c := &HTTPConnectionRequester{
+		Store: store,
+		Credentials: func(device cwmp.Identity) (string, string, error) {
+			return "admin", "secret", nil
+		},
+	}
+
+	err := c.ConnectionRequest(context.Background(), testDevice)
+	if err != ErrNoURL {
+		t.Fatalf("Expected (%v) got (%v)", ErrNoURL, err)
+	}
+
+	s := &Server{Store: store}
+
+	post(t, s, `<cwmp:Inform><DeviceId><OUI>E48D8C</OUI><ProductClass>hAP</ProductClass><SerialNumber>1</SerialNumber></DeviceId><ParameterList><ParameterValueStruct><Name>Device.ManagementServer.ConnectionRequestURL</Name><Value>`+srv.URL+`/cr</Value></ParameterValueStruct></ParameterList></cwmp:Inform>`)
+
+	err = c.ConnectionRequest(context.Background(), testDevice)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestAuthorizationMixedChallenges(t *testing.T) {
+	u, _ := url.Parse("http://cpe/cr")
+
+	auth, err := authorization([]string{
+		`Digest realm="cpe", nonce="abc", algorithm=SHA-512-256`,
+		`Digest realm="cpe", nonce="abc", algorithm=MD5`,
+		`Basic realm="cpe"`,
+	}, u, "admin", "secret")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	scheme, params := parseChallenge(auth)
+	if scheme != "digest" || params["algorithm"] != "MD5" {
+		t.Fatalf("Expected an MD5 Digest got (%s)", auth)
+	}
+
+	_, err = authorization([]string{`Digest realm="cpe", nonce="abc", algorithm=SHA-512-256`}, u, "admin", "secret")
+	if err != ErrAuthFailed {
+		t.Fatalf("Expected (%v) got (%v)", ErrAuthFailed, err)
+	}
+}
+
+func TestConnectionRequestMixedChallenges(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			w.Header().Add("WWW-Authenticate", `Digest realm="cpe", nonce="abc", algorithm=SHA-512-256`)
+			w.Header().Add("WWW-Authenticate", `Basic realm="cpe"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}))
+	defer srv.Close()
+
+	err := ConnectionRequest(context.Background(), nil, srv.URL, "admin", "secret")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestConnectionRequestStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	err := ConnectionRequest(context.Background(), nil, srv.URL, "", "")
+	if !errors.Is(err, ErrUnreachable) {
+		t.Fatalf("Expected (%v) got (%v)", ErrUnreachable, err)
+	}
+}
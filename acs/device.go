@@ -0,0 +1,201 @@
+package acs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+// ErrTaskExpired is returned for a call whose task expired before it could
+// be sent.
+var ErrTaskExpired = errors.New("acs: Task expired")
+
+// ConnectionRequester asks a device to open a session with the ACS.
+type ConnectionRequester interface {
+	ConnectionRequest(ctx context.Context, device cwmp.Identity) error
+}
+
+// ConnectionRequesterFunc adapts a function to a ConnectionRequester.
+type ConnectionRequesterFunc func(ctx context.Context, device cwmp.Identity) error
+
+func (f ConnectionRequesterFunc) ConnectionRequest(ctx context.Context, device cwmp.Identity) error {
+	return f(ctx, device)
+}
+
+// DeviceClient calls RPCs on a device and waits for the responses.
+type DeviceClient struct {
+	ID cwmp.Identity
+
+	server *Server
+}
+
+// Device returns a client for the device with the given identity.
+func (s *Server) Device(id cwmp.Identity) *DeviceClient {
+	return &DeviceClient{ID: id, server: s}
+}
+
+// inSession reports whether device has a session in progress that will
+// still pick up queued tasks. Sessions that timed out, or that have no more
+// requests to send, don't count.
+func (s *Server) inSession(device cwmp.Identity) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	for _, sess := range s.sessions {
+		if sess.device == device && !sess.closing && now.Sub(sess.lastSeen) <= sess.timeout {
+			return true
+		}
+	}
+
+	return false
+}
+
+// wait registers ch to receive the task with the given ID once it finishes.
+func (s *Server) wait(id string, ch chan *Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.waiters == nil {
+		s.waiters = make(map[string]chan *Task)
+	}
+
+	s.waiters[id] = ch
+}
+
+func (s *Server) unwait(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.waiters, id)
+}
+
+func (s *Server) notify(t *Task) {
+	s.mu.Lock()
+	ch := s.waiters[t.ID]
+	delete(s.waiters, t.ID)
+	s.mu.Unlock()
+
+	if ch != nil {
+		ch <- t
+	}
+}
+
+// cancelTask stops waiting for a task and expires it if it hasn't been sent.
+func (s *Server) cancelTask(t *Task) {
+	s.unwait(t.ID)
+	s.expireTask(t)
+}
+
+// Call queues req for the device, sends it a connection request unless it
+// is in a session already, and waits for the response. A fault from the
+// device is returned as a *cwmp.Fault. If ctx is done before the request is
+// sent the task is expired, if it has a deadline the task expires with it.
+// If ctx is done after the request was sent it can't be taken back: the
+// task still finishes, but its result only reaches OnTaskFinished.
+func (d *DeviceClient) Call(ctx context.Context, req cwmp.Message) (cwmp.Message, error) {
+	s := d.server
+
+	expires, _ := ctx.Deadline()
+
+	ch := make(chan *Task, 1)
+
+	t, err := s.enqueue(d.ID, req, expires, ch)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.ConnectionRequester != nil && !s.inSession(d.ID) {
+		err = s.ConnectionRequester.ConnectionRequest(ctx, d.ID)
+		if err != nil {
+			s.cancelTask(t)
+			return nil, fmt.Errorf("acs: Connection request to (%s): %w", d.ID, err)
+		}
+	}
+
+	select {
+	case t = <-ch:
+	case <-ctx.Done():
+		s.cancelTask(t)
+		return nil, ctx.Err()
+	}
+
+	switch t.State {
+	case TaskDone:
+		return t.Response, nil
+	case TaskFaulted:
+		return nil, t.Fault
+	}
+
+	return nil, ErrTaskExpired
+}
+
+// GetParameterValues gets names in a single request, batched with
+// NewGetParameterValues.
+func (d *DeviceClient) GetParameterValues(ctx context.Context, names []string) (*cwmp.GetParameterValuesResponse, error) {
+	resp, err := d.Call(ctx, NewGetParameterValues(names...))
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*cwmp.GetParameterValuesResponse), nil
+}
+
+func (d *DeviceClient) SetParameterValues(ctx context.Context, params []cwmp.ParameterValue, parameterKey string) (*cwmp.SetParameterValuesResponse, error) {
+	resp, err := d.Call(ctx, &cwmp.SetParameterValues{ParameterList: params, ParameterKey: parameterKey})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*cwmp.SetParameterValuesResponse), nil
+}
+
+func (d *DeviceClient) GetParameterNames(ctx context.Context, path string, nextLevel bool) (*cwmp.GetParameterNamesResponse, error) {
+	resp, err := d.Call(ctx, &cwmp.GetParameterNames{ParameterPath: path, NextLevel: nextLevel})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*cwmp.GetParameterNamesResponse), nil
+}
+
+func (d *DeviceClient) AddObject(ctx context.Context, name, parameterKey string) (*cwmp.AddObjectResponse, error) {
+	resp, err := d.Call(ctx, &cwmp.AddObject{ObjectName: name, ParameterKey: parameterKey})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*cwmp.AddObjectResponse), nil
+}
+
+func (d *DeviceClient) DeleteObject(ctx context.Context, name, parameterKey string) (*cwmp.DeleteObjectResponse, error) {
+	resp, err := d.Call(ctx, &cwmp.DeleteObject{ObjectName: name, ParameterKey: parameterKey})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*cwmp.DeleteObjectResponse), nil
+}
+
+func (d *DeviceClient) Download(ctx context.Context, req *cwmp.Download) (*cwmp.DownloadResponse, error) {
+	resp, err := d.Call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*cwmp.DownloadResponse), nil
+}
+
+func (d *DeviceClient) Reboot(ctx context.Context, commandKey string) error {
+	_, err := d.Call(ctx, &cwmp.Reboot{CommandKey: commandKey})
+	return err
+}
+
+func (d *DeviceClient) FactoryReset(ctx context.Context) error {
+	_, err := d.Call(ctx, &cwmp.FactoryReset{})
+	return err
+}
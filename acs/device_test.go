@@ -0,0 +1,344 @@
+package acs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+// cpe returns a connection requester that runs a session for the device,
+// answering the first ACS request with body.
+func cpe(t *testing.T, s *Server, body string) ConnectionRequester {
+	return ConnectionRequesterFunc(func(ctx context.Context, device cwmp.Identity) error {
+		go func() {
+			for _, r := range []struct {
+				id   string
+				body string
+			}{
+				{"", testInform},
+				{"", ""},
+				{"1", body},
+			} {
+				_, _, err := s.handleMessage(newRequest(r.id, r.body))
+				if err != nil {
+					t.Errorf("err: %v", err)
+					return
+				}
+			}
+		}()
+
+		return nil
+	})
+}
+
+func TestDeviceGetParameterValues(t *testing.T) {
+	s := &Server{}
+	s.ConnectionRequester = cpe(t, s, `<cwmp:GetParameterValuesResponse><ParameterList><ParameterValueStruct><Name>Device.DeviceInfo.SoftwareVersion</Name><Value>7.1</Value></ParameterValueStruct></ParameterList></cwmp:GetParameterValuesResponse>`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	resp, err := s.Device(testDevice).GetParameterValues(ctx, []string{"Device.DeviceInfo.SoftwareVersion", "Device.DeviceInfo.SoftwareVersion"})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if len(resp.ParameterList) != 1 || resp.ParameterList[0].Value != "7.1" {
+		t.Fatalf("Unexpected response (%v)", resp)
+	}
+
+	tasks, err := s.store().Tasks(testDevice)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := tasks[0].Request.(*cwmp.GetParameterValues)
+	if len(req.ParameterNames) != 1 {
+		t.Fatalf("Expected the names to be batched got (%v)", req.ParameterNames)
+	}
+}
+
+func TestDeviceFault(t *testing.T) {
+	s := &Server{}
+	s.ConnectionRequester = cpe(t, s, testFault)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.Device(testDevice).Reboot(ctx, "reboot")
+
+	var f *cwmp.Fault
+	if !errors.As(err, &f) || f.Code != cwmp.CPERequestDenied {
+		t.Fatalf("Expected Fault (%d) got (%v)", cwmp.CPERequestDenied, err)
+	}
+}
+
+func TestDeviceTimeout(t *testing.T) {
+	var finished *Task
+
+	s := &Server{
+		OnTaskFinished: func(t *Task) {
+			finished = t
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := s.Device(testDevice).GetParameterValues(ctx, []string{"Device."})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected (%v) got (%v)", context.DeadlineExceeded, err)
+	}
+
+	if finished == nil || finished.State != TaskExpired {
+		t.Fatalf("Unexpected task (%v)", finished)
+	}
+
+	post(t, s, testInform)
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if msg != nil {
+		t.Fatalf("Expected session to end got (%T)", msg.Body)
+	}
+}
+
+func TestDeviceConnectionRequestFailed(t *testing.T) {
+	failed := errors.New("unreachable")
+
+	s := &Server{
+		ConnectionRequester: ConnectionRequesterFunc(func(ctx context.Context, device cwmp.Identity) error {
+			return failed
+		}),
+	}
+
+	err := s.Device(testDevice).FactoryReset(context.Background())
+	if !errors.Is(err, failed) {
+		t.Fatalf("Expected (%v) got (%v)", failed, err)
+	}
+
+	tasks, err := s.store().Tasks(testDevice)
+	if err != nil || len(tasks) != 1 || tasks[0].State != TaskExpired {
+		t.Fatalf("Unexpected tasks (%v) (%v)", tasks, err)
+	}
+}
+
+func TestDeviceInSession(t *testing.T) {
+	s := &Server{
+		ConnectionRequester: ConnectionRequesterFunc(func(ctx context.Context, device cwmp.Identity) error {
+			return errors.New("Unexpected connection request")
+		}),
+	}
+
+	post(t, s, testInform)
+
+	done := make(chan error)
+
+	go func() {
+		done <- s.Device(testDevice).Reboot(context.Background(), "")
+	}()
+
+	for {
+		tasks, _ := s.store().Tasks(testDevice)
+		if len(tasks) == 1 {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, r := range []struct {
+		id   string
+		body string
+	}{
+		{"", ""},
+		{"1", `<cwmp:RebootResponse></cwmp:RebootResponse>`},
+	} {
+		_, _, err := s.handleMessage(newRequest(r.id, r.body))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	err := <-done
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestDeviceSessionTimedOut(t *testing.T) {
+	requests := make(chan cwmp.Identity, 1)
+
+	s := &Server{
+		SessionTimeout: 100 * time.Millisecond,
+		ConnectionRequester: ConnectionRequesterFunc(func(ctx context.Context, device cwmp.Identity) error {
+			requests <- device
+			return nil
+		}),
+	}
+
+	post(t, s, testInform)
+
+	// Timed out but not ended yet.
+	s.mu.Lock()
+	for _, sess := range s.sessions {
+		sess.lastSeen = time.Now().Add(-time.Second)
+	}
+	s.mu.Unlock()
+
+	if s.inSession(testDevice) {
+		t.Fatal("Expected a timed out session not to count")
+	}
+
+	time.Sleep(300 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	s.Device(testDevice).Reboot(ctx, "")
+
+	select {
+	case got := <-requests:
+		if got != testDevice {
+			t.Fatalf("Expected (%s) got (%s)", testDevice, got)
+		}
+	default:
+		t.Fatal("Expected a connection request")
+	}
+}
+
+func TestDeviceQueuedWhileClosing(t *testing.T) {
+	reboot := &cwmp.Reboot{CommandKey: "late"}
+
+	var s *Server
+	s = &Server{
+		// Stands in for a call queued just after the session looked for
+		// tasks.
+		NextRequest: func(device cwmp.Identity) cwmp.Message {
+			if reboot != nil {
+				_, err := s.Enqueue(device, reboot, time.Time{})
+				if err != nil {
+					t.Fatalf("err: %v", err)
+				}
+
+				reboot = nil
+			}
+
+			return nil
+		},
+	}
+
+	post(t, s, testInform)
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if msg == nil {
+		t.Fatal("Expected the queued task to be sent")
+	}
+
+	if r, ok := msg.Body.(*cwmp.Reboot); !ok || r.CommandKey != "late" {
+		t.Fatalf("Expected Reboot got (%v)", msg.Body)
+	}
+
+	if !s.inSession(testDevice) {
+		t.Fatal("Expected the session to count again")
+	}
+}
+
+func TestDeviceCancelPrefetched(t *testing.T) {
+	var finished []*Task
+
+	s := &Server{
+		HoldRequests: true,
+		OnTaskFinished: func(t *Task) {
+			finished = append(finished, t)
+		},
+	}
+
+	task, err := s.Enqueue(testDevice, &cwmp.Reboot{}, time.Time{})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The Inform prefetches the task to decide whether to hold requests.
+	post(t, s, testInform)
+
+	s.cancelTask(task)
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if msg != nil {
+		t.Fatalf("Expected session to end got (%T)", msg.Body)
+	}
+
+	assertTask(t, s, task.ID, TaskExpired)
+
+	if len(finished) != 1 {
+		t.Fatalf("Expected 1 finished task got (%d)", len(finished))
+	}
+}
+
+func TestDeviceCancelSent(t *testing.T) {
+	finished := make(chan *Task, 1)
+
+	s := &Server{
+		OnTaskFinished: func(t *Task) {
+			finished <- t
+		},
+	}
+
+	post(t, s, testInform)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+
+	go func() {
+		done <- s.Device(testDevice).Reboot(ctx, "")
+	}()
+
+	for {
+		tasks, _ := s.store().Tasks(testDevice)
+		if len(tasks) == 1 {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if _, ok := msg.Body.(*cwmp.Reboot); !ok {
+		t.Fatalf("Expected Reboot got (%T)", msg.Body)
+	}
+
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Expected (%v) got (%v)", context.Canceled, err)
+	}
+
+	_, _, err = s.handleMessage(newRequest("1", `<cwmp:RebootResponse></cwmp:RebootResponse>`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if task := <-finished; task.State != TaskDone {
+		t.Fatalf("Expected (%s) got (%s)", TaskDone, task.State)
+	}
+}
@@ -0,0 +1,113 @@
+package acs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+type duKey struct {
+	device     cwmp.Identity
+	commandKey string
+}
+
+// maxDUResults is how many results are kept per ChangeDUState request, or
+// per device for autonomous changes. The oldest are dropped first.
+const maxDUResults = 64
+
+// duResults holds the outcome of software module operations reported by CPEs,
+// by device and the CommandKey of the ChangeDUState request. Autonomous
+// changes have no CommandKey and are kept by device. They are only held in
+// memory, for at most the server's TaskRetention since they were last
+// reported.
+type duResults struct {
+	mu         sync.Mutex
+	results    map[duKey]opResults
+	autonomous map[cwmp.Identity]autonOpResults
+}
+
+type opResults struct {
+	results  []cwmp.OpResult
+	reported time.Time
+}
+
+type autonOpResults struct {
+	results  []cwmp.AutonOpResult
+	reported time.Time
+}
+
+func (r *duResults) complete(device cwmp.Identity, m *cwmp.DUStateChangeComplete, now time.Time, retention time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.results == nil {
+		r.results = make(map[duKey]opResults)
+	}
+
+	r.expire(now, retention)
+
+	k := duKey{device, m.CommandKey}
+
+	res := append(r.results[k].results, m.Results...)
+	if len(res) > maxDUResults {
+		res = res[len(res)-maxDUResults:]
+	}
+
+	r.results[k] = opResults{res, now}
+}
+
+func (r *duResults) autonomousComplete(device cwmp.Identity, m *cwmp.AutonomousDUStateChangeComplete, now time.Time, retention time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.autonomous == nil {
+		r.autonomous = make(map[cwmp.Identity]autonOpResults)
+	}
+
+	r.expire(now, retention)
+
+	res := append(r.autonomous[device].results, m.Results...)
+	if len(res) > maxDUResults {
+		res = res[len(res)-maxDUResults:]
+	}
+
+	r.autonomous[device] = autonOpResults{res, now}
+}
+
+// expire forgets results last reported longer than retention ago.
+func (r *duResults) expire(now time.Time, retention time.Duration) {
+	for k, res := range r.results {
+		if now.Sub(res.reported) > retention {
+			delete(r.results, k)
+		}
+	}
+
+	for device, res := range r.autonomous {
+		if now.Sub(res.reported) > retention {
+			delete(r.autonomous, device)
+		}
+	}
+}
+
+// DUStateChangeResults returns the results device reported for the
+// ChangeDUState request with commandKey. Results are kept in memory for
+// TaskRetention, so they are lost on restart.
+func (s *Server) DUStateChangeResults(device cwmp.Identity, commandKey string) ([]cwmp.OpResult, bool) {
+	s.duResults.mu.Lock()
+	defer s.duResults.mu.Unlock()
+
+	res, ok := s.duResults.results[duKey{device, commandKey}]
+
+	return append([]cwmp.OpResult(nil), res.results...), ok
+}
+
+// AutonomousDUStateChangeResults returns the results of the software module
+// operations device reported making on its own, kept like those of
+// DUStateChangeResults.
+func (s *Server) AutonomousDUStateChangeResults(device cwmp.Identity) []cwmp.AutonOpResult {
+	s.duResults.mu.Lock()
+	defer s.duResults.mu.Unlock()
+
+	return append([]cwmp.AutonOpResult(nil), s.duResults.autonomous[device].results...)
+}
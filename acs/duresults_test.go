@@ -0,0 +1,70 @@
+package acs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+func TestServerDUStateChangeResults(t *testing.T) {
+	s := &Server{}
+
+	other := cwmp.Identity{OUI: "E48D8C", ProductClass: "hAP", SerialNumber: "2"}
+
+	if _, ok := s.DUStateChangeResults(testDevice, "apps"); ok {
+		t.Fatal("Expected no results")
+	}
+
+	post(t, s, testInform)
+	post(t, s, `<cwmp:DUStateChangeComplete><Results><OpResultStruct><UUID>1</UUID><CurrentState>Installed</CurrentState></OpResultStruct></Results><CommandKey>apps</CommandKey></cwmp:DUStateChangeComplete>`)
+	post(t, s, `<cwmp:AutonomousDUStateChangeComplete><Results><AutonOpResultStruct><UUID>3</UUID><OperationPerformed>Install</OperationPerformed></AutonOpResultStruct></Results></cwmp:AutonomousDUStateChangeComplete>`)
+
+	post(t, s, `<cwmp:Inform><DeviceId><OUI>E48D8C</OUI><ProductClass>hAP</ProductClass><SerialNumber>2</SerialNumber></DeviceId></cwmp:Inform>`)
+	post(t, s, `<cwmp:DUStateChangeComplete><Results><OpResultStruct><UUID>2</UUID><CurrentState>Failed</CurrentState></OpResultStruct></Results><CommandKey>apps</CommandKey></cwmp:DUStateChangeComplete>`)
+
+	got, ok := s.DUStateChangeResults(testDevice, "apps")
+	if !ok || len(got) != 1 || got[0].UUID != "1" || got[0].CurrentState != cwmp.DUStateInstalled {
+		t.Fatalf("Unexpected results (%v)", got)
+	}
+
+	got, ok = s.DUStateChangeResults(other, "apps")
+	if !ok || len(got) != 1 || got[0].UUID != "2" {
+		t.Fatalf("Unexpected results (%v)", got)
+	}
+
+	auton := s.AutonomousDUStateChangeResults(testDevice)
+	if len(auton) != 1 || auton[0].UUID != "3" || auton[0].OperationPerformed != "Install" {
+		t.Fatalf("Unexpected autonomous results (%v)", auton)
+	}
+
+	if len(s.AutonomousDUStateChangeResults(other)) != 0 {
+		t.Fatal("Expected no autonomous results")
+	}
+}
+
+func TestDUResultsExpire(t *testing.T) {
+	r := &duResults{}
+	now := time.Now()
+
+	other := cwmp.Identity{OUI: "E48D8C", ProductClass: "hAP", SerialNumber: "2"}
+
+	r.complete(testDevice, &cwmp.DUStateChangeComplete{CommandKey: "apps"}, now, time.Hour)
+	r.autonomousComplete(testDevice, &cwmp.AutonomousDUStateChangeComplete{}, now, time.Hour)
+
+	for i := 0; i < maxDUResults+1; i++ {
+		r.complete(other, &cwmp.DUStateChangeComplete{CommandKey: "apps", Results: []cwmp.OpResult{cwmp.OpResult{}}}, now.Add(2*time.Hour), time.Hour)
+	}
+
+	if _, ok := r.results[duKey{testDevice, "apps"}]; ok {
+		t.Fatal("Expected results to expire")
+	}
+
+	if _, ok := r.autonomous[testDevice]; ok {
+		t.Fatal("Expected autonomous results to expire")
+	}
+
+	if got := len(r.results[duKey{other, "apps"}].results); got != maxDUResults {
+		t.Fatalf("Expected (%d) got (%d)", maxDUResults, got)
+	}
+}
@@ -0,0 +1,480 @@
+package acs
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+// FileStore is a Store that keeps a JSON file per device and per session
+// under Dir, so that it survives restarts. Files are replaced atomically.
+type FileStore struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// storedMessage holds a request or response in its XML form, decoded with
+// the method registered under Method.
+type storedMessage struct {
+	Method   string
+	Response bool `json:",omitempty"`
+	XML      string
+}
+
+type fileTask struct {
+	ID       string
+	Request  *storedMessage
+	State    TaskState
+	Created  time.Time
+	Expires  time.Time
+	Sent     time.Time
+	Finished time.Time
+	Response *storedMessage `json:",omitempty"`
+	Fault    *cwmp.Fault    `json:",omitempty"`
+}
+
+type fileDevice struct {
+	Device     *Device               `json:",omitempty"`
+	Inform     *storedMessage        `json:",omitempty"`
+	Parameters []cwmp.ParameterValue `json:",omitempty"`
+	Tasks      []fileTask            `json:",omitempty"`
+}
+
+func encodeMessage(msg cwmp.Message) (*storedMessage, error) {
+	b, err := xml.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &storedMessage{
+		Method:   msg.Method().Name,
+		Response: msg.IsResponse(),
+		XML:      string(b),
+	}, nil
+}
+
+func decodeMessage(m *storedMessage) (cwmp.Message, error) {
+	var msg cwmp.Message = &cwmp.Unknown{}
+
+	method, ok := cwmp.Lookup(m.Method)
+	if ok && m.Response && method.Response != nil {
+		msg = method.NewResponse()
+	} else if ok && !m.Response && method.Request != nil {
+		msg = method.NewRequest()
+	}
+
+	err := xml.Unmarshal([]byte(m.XML), msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func (s *FileStore) devicePath(id cwmp.Identity) string {
+	return filepath.Join(s.Dir, "devices", fileName(id.String())+".json")
+}
+
+func (s *FileStore) sessionPath(id string) string {
+	return filepath.Join(s.Dir, "sessions", fileName(id)+".json")
+}
+
+func readJSON(p string, v interface{}) error {
+	b, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+func writeJSON(p string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(p), ".store")
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(b)
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	err = os.Rename(f.Name(), p)
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
+
+// jsonFiles lists the JSON files in a directory of the store.
+func jsonFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+
+	for _, info := range infos {
+		if strings.HasSuffix(info.Name(), ".json") {
+			files = append(files, filepath.Join(dir, info.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+func (s *FileStore) readDevice(id cwmp.Identity) (*fileDevice, error) {
+	d := &fileDevice{}
+
+	err := readJSON(s.devicePath(id), d)
+	if err == ErrNotFound {
+		return d, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// updateDevice reads the file of the device, passes it to fn and writes it
+// back if fn succeeds.
+func (s *FileStore) updateDevice(id cwmp.Identity, fn func(d *fileDevice) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.readDevice(id)
+	if err != nil {
+		return err
+	}
+
+	err = fn(d)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(s.devicePath(id), d)
+}
+
+func (s *FileStore) Device(id cwmp.Identity) (*Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.readDevice(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Device == nil {
+		return nil, ErrNotFound
+	}
+
+	return d.Device, nil
+}
+
+func (s *FileStore) Devices() ([]*Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := jsonFiles(filepath.Join(s.Dir, "devices"))
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []*Device{}
+
+	for _, p := range files {
+		d := &fileDevice{}
+
+		err = readJSON(p, d)
+		if err != nil {
+			return nil, err
+		}
+
+		if d.Device != nil {
+			devices = append(devices, d.Device)
+		}
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].ID.String() < devices[j].ID.String()
+	})
+
+	return devices, nil
+}
+
+func (s *FileStore) PutDevice(dev *Device) error {
+	return s.updateDevice(dev.ID, func(d *fileDevice) error {
+		c := *dev
+		d.Device = &c
+		return nil
+	})
+}
+
+func (s *FileStore) DeleteDevice(id cwmp.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.readDevice(id)
+	if err != nil {
+		return err
+	}
+
+	if d.Device == nil {
+		return ErrNotFound
+	}
+
+	return os.Remove(s.devicePath(id))
+}
+
+func (s *FileStore) LastInform(id cwmp.Identity) (*cwmp.Inform, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.readDevice(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Inform == nil {
+		return nil, ErrNotFound
+	}
+
+	m := &cwmp.Inform{}
+
+	err = xml.Unmarshal([]byte(d.Inform.XML), m)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (s *FileStore) PutInform(id cwmp.Identity, m *cwmp.Inform) error {
+	stored, err := encodeMessage(m)
+	if err != nil {
+		return err
+	}
+
+	return s.updateDevice(id, func(d *fileDevice) error {
+		d.Inform = stored
+		return nil
+	})
+}
+
+func (s *FileStore) Parameters(id cwmp.Identity) ([]cwmp.ParameterValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.readDevice(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Parameters, nil
+}
+
+func (s *FileStore) PutParameters(id cwmp.Identity, params []cwmp.ParameterValue) error {
+	return s.updateDevice(id, func(d *fileDevice) error {
+		d.Parameters = mergeParameters(d.Parameters, params)
+		return nil
+	})
+}
+
+func (s *FileStore) Tasks(id cwmp.Identity) ([]*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, err := s.readDevice(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*Task, 0, len(d.Tasks))
+
+	for _, ft := range d.Tasks {
+		t := &Task{
+			ID:       ft.ID,
+			Device:   id,
+			State:    ft.State,
+			Created:  ft.Created,
+			Expires:  ft.Expires,
+			Sent:     ft.Sent,
+			Finished: ft.Finished,
+			Fault:    ft.Fault,
+		}
+
+		t.Request, err = decodeMessage(ft.Request)
+		if err != nil {
+			return nil, err
+		}
+
+		if ft.Response != nil {
+			t.Response, err = decodeMessage(ft.Response)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		tasks = append(tasks, t)
+	}
+
+	return tasks, nil
+}
+
+func (s *FileStore) PutTask(t *Task) error {
+	ft := fileTask{
+		ID:       t.ID,
+		State:    t.State,
+		Created:  t.Created,
+		Expires:  t.Expires,
+		Sent:     t.Sent,
+		Finished: t.Finished,
+		Fault:    t.Fault,
+	}
+
+	var err error
+
+	ft.Request, err = encodeMessage(t.Request)
+	if err != nil {
+		return err
+	}
+
+	if t.Response != nil {
+		ft.Response, err = encodeMessage(t.Response)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.updateDevice(t.Device, func(d *fileDevice) error {
+		for i := range d.Tasks {
+			if d.Tasks[i].ID == t.ID {
+				d.Tasks[i] = ft
+				return nil
+			}
+		}
+
+		d.Tasks = append(d.Tasks, ft)
+
+		return nil
+	})
+}
+
+func (s *FileStore) DeleteTask(id cwmp.Identity, taskID string) error {
+	return s.updateDevice(id, func(d *fileDevice) error {
+		for i := range d.Tasks {
+			if d.Tasks[i].ID == taskID {
+				d.Tasks = append(d.Tasks[:i], d.Tasks[i+1:]...)
+				return nil
+			}
+		}
+
+		return ErrNotFound
+	})
+}
+
+func (s *FileStore) Session(id string) (*SessionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := &SessionState{}
+
+	err := readJSON(s.sessionPath(id), state)
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func (s *FileStore) Sessions() ([]*SessionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := jsonFiles(filepath.Join(s.Dir, "sessions"))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []*SessionState{}
+
+	for _, p := range files {
+		state := &SessionState{}
+
+		err = readJSON(p, state)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, state)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+
+	return sessions, nil
+}
+
+func (s *FileStore) PutSession(state *SessionState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeJSON(s.sessionPath(state.ID), state)
+}
+
+func (s *FileStore) DeleteSession(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.sessionPath(id))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,115 @@
+package acs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+// followUps remembers requests a device accepted whose outcome is reported by
+// a later Inform rather than in the session they were sent in, so the Inform
+// can be linked back to the request that caused it. They are only held in
+// memory, for at most the server's TaskRetention and no more than
+// maxFollowUps per device.
+type followUps struct {
+	mu      sync.Mutex
+	pending map[cwmp.Identity][]followUp
+}
+
+// maxFollowUps is how many requests are remembered per device. The oldest
+// are forgotten first.
+const maxFollowUps = 16
+
+type followUp struct {
+	req  cwmp.Message
+	sent time.Time
+}
+
+// hasFollowUp reports whether req is answered by a later Inform.
+func hasFollowUp(req cwmp.Message) bool {
+	switch req.(type) {
+	case *cwmp.ScheduleInform, *cwmp.FactoryReset:
+		return true
+	}
+
+	return false
+}
+
+func followsFrom(req cwmp.Message, inform *cwmp.Inform) bool {
+	switch r := req.(type) {
+	case *cwmp.ScheduleInform:
+		return inform.HasEvent(cwmp.EventScheduled) && inform.HasCompletion("ScheduleInform", r.CommandKey)
+	case *cwmp.FactoryReset:
+		return inform.HasEvent(cwmp.EventBootstrap)
+	}
+
+	return false
+}
+
+func (f *followUps) add(device cwmp.Identity, req cwmp.Message, now time.Time, retention time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.pending == nil {
+		f.pending = make(map[cwmp.Identity][]followUp)
+	}
+
+	f.expire(now, retention)
+
+	pending := append(f.pending[device], followUp{req, now})
+	if len(pending) > maxFollowUps {
+		pending = pending[len(pending)-maxFollowUps:]
+	}
+
+	f.pending[device] = pending
+}
+
+// expire forgets requests sent longer than retention ago, whose follow-up
+// is never going to arrive.
+func (f *followUps) expire(now time.Time, retention time.Duration) {
+	for device, pending := range f.pending {
+		var remaining []followUp
+
+		for _, p := range pending {
+			if now.Sub(p.sent) <= retention {
+				remaining = append(remaining, p)
+			}
+		}
+
+		if len(remaining) == 0 {
+			delete(f.pending, device)
+		} else {
+			f.pending[device] = remaining
+		}
+	}
+}
+
+// match returns the pending requests for device that inform is a follow-up
+// to and forgets about them.
+func (f *followUps) match(device cwmp.Identity, inform *cwmp.Inform, now time.Time, retention time.Duration) []cwmp.Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.expire(now, retention)
+
+	var matched []cwmp.Message
+	var remaining []followUp
+
+	for _, p := range f.pending[device] {
+		if followsFrom(p.req, inform) {
+			matched = append(matched, p.req)
+			continue
+		}
+
+		remaining = append(remaining, p)
+	}
+
+	if len(remaining) == 0 {
+		delete(f.pending, device)
+	} else {
+		f.pending[device] = remaining
+	}
+
+	return matched
+}
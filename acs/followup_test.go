@@ -0,0 +1,153 @@
+package acs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+func TestFollowUpsScheduleInform(t *testing.T) {
+	f := &followUps{}
+	now := time.Now()
+
+	req := &cwmp.ScheduleInform{DelaySeconds: 10, CommandKey: "wake"}
+	other := &cwmp.ScheduleInform{DelaySeconds: 10, CommandKey: "other"}
+
+	f.add(testDevice, req, now, time.Hour)
+	f.add(testDevice, other, now, time.Hour)
+
+	inform := &cwmp.Inform{
+		Event: []cwmp.Event{
+			cwmp.Event{EventCode: "3 SCHEDULED"},
+			cwmp.Event{EventCode: "M ScheduleInform", CommandKey: "wake"},
+		},
+	}
+
+	if got := f.match(cwmp.Identity{OUI: "000000"}, inform, now, time.Hour); len(got) != 0 {
+		t.Fatalf("Expected no matches got (%d)", len(got))
+	}
+
+	got := f.match(testDevice, inform, now, time.Hour)
+	if len(got) != 1 || got[0] != req {
+		t.Fatalf("Expected (%v) got (%v)", req, got)
+	}
+
+	if got := f.match(testDevice, inform, now, time.Hour); len(got) != 0 {
+		t.Fatalf("Expected no matches got (%d)", len(got))
+	}
+
+	if len(f.pending[testDevice]) != 1 {
+		t.Fatalf("Expected 1 pending request got (%d)", len(f.pending[testDevice]))
+	}
+}
+
+func TestFollowUpsFactoryReset(t *testing.T) {
+	f := &followUps{}
+	now := time.Now()
+
+	req := &cwmp.FactoryReset{}
+
+	f.add(testDevice, req, now, time.Hour)
+
+	periodic := &cwmp.Inform{Event: []cwmp.Event{cwmp.Event{EventCode: "2 PERIODIC"}}}
+	if got := f.match(testDevice, periodic, now, time.Hour); len(got) != 0 {
+		t.Fatalf("Expected no matches got (%d)", len(got))
+	}
+
+	bootstrap := &cwmp.Inform{
+		Event: []cwmp.Event{
+			cwmp.Event{EventCode: "0 BOOTSTRAP"},
+			cwmp.Event{EventCode: "1 BOOT"},
+		},
+	}
+
+	got := f.match(testDevice, bootstrap, now, time.Hour)
+	if len(got) != 1 || got[0] != req {
+		t.Fatalf("Expected (%v) got (%v)", req, got)
+	}
+
+	if _, ok := f.pending[testDevice]; ok {
+		t.Fatal("Expected no pending requests")
+	}
+}
+
+func TestFollowUpsExpire(t *testing.T) {
+	f := &followUps{}
+	now := time.Now()
+
+	f.add(testDevice, &cwmp.FactoryReset{}, now, time.Hour)
+
+	for i := 0; i < maxFollowUps+1; i++ {
+		f.add(testDevice, &cwmp.ScheduleInform{CommandKey: "wake"}, now.Add(time.Minute), time.Hour)
+	}
+
+	if len(f.pending[testDevice]) != maxFollowUps {
+		t.Fatalf("Expected (%d) got (%d)", maxFollowUps, len(f.pending[testDevice]))
+	}
+
+	f.add(cwmp.Identity{OUI: "000000"}, &cwmp.FactoryReset{}, now, time.Hour)
+
+	bootstrap := &cwmp.Inform{Event: []cwmp.Event{cwmp.Event{EventCode: "0 BOOTSTRAP"}}}
+
+	if got := f.match(cwmp.Identity{OUI: "000000"}, bootstrap, now.Add(2*time.Hour), time.Hour); len(got) != 0 {
+		t.Fatalf("Expected no matches got (%d)", len(got))
+	}
+
+	if len(f.pending) != 0 {
+		t.Fatalf("Expected no pending requests got (%d)", len(f.pending))
+	}
+}
+
+func TestServerFollowUp(t *testing.T) {
+	var got []cwmp.Message
+
+	s := &Server{
+		OnFollowUp: func(device cwmp.Identity, m *cwmp.Inform, req cwmp.Message) {
+			got = append(got, req)
+		},
+	}
+
+	task, err := s.Enqueue(testDevice, &cwmp.ScheduleInform{DelaySeconds: 60, CommandKey: "wake"}, time.Time{})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	post(t, s, testInform)
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertRequest(t, msg, "1", task.Request)
+
+	_, msg, err = s.handleMessage(newRequest("1", `<cwmp:ScheduleInformResponse></cwmp:ScheduleInformResponse>`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if msg != nil {
+		t.Fatalf("Expected session to end got (%T)", msg.Body)
+	}
+
+	if len(got) != 0 {
+		t.Fatalf("Expected no follow-ups got (%v)", got)
+	}
+
+	post(t, s, `<cwmp:Inform><DeviceId><OUI>E48D8C</OUI><ProductClass>hAP</ProductClass><SerialNumber>1</SerialNumber></DeviceId><Event><EventStruct><EventCode>2 PERIODIC</EventCode><CommandKey></CommandKey></EventStruct></Event></cwmp:Inform>`)
+
+	if len(got) != 0 {
+		t.Fatalf("Expected no follow-ups got (%v)", got)
+	}
+
+	post(t, s, `<cwmp:Inform><DeviceId><OUI>E48D8C</OUI><ProductClass>hAP</ProductClass><SerialNumber>1</SerialNumber></DeviceId><Event><EventStruct><EventCode>3 SCHEDULED</EventCode><CommandKey></CommandKey></EventStruct><EventStruct><EventCode>M ScheduleInform</EventCode><CommandKey>wake</CommandKey></EventStruct></Event></cwmp:Inform>`)
+
+	if len(got) != 1 {
+		t.Fatalf("Expected 1 follow-up got (%d)", len(got))
+	}
+
+	if req, ok := got[0].(*cwmp.ScheduleInform); !ok || req.CommandKey != "wake" {
+		t.Fatalf("Expected the ScheduleInform got (%v)", got[0])
+	}
+}
@@ -0,0 +1,235 @@
+package acs
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+// MemoryStore is a Store that keeps everything in memory. The zero value is
+// an empty store.
+type MemoryStore struct {
+	mu         sync.Mutex
+	devices    map[cwmp.Identity]*Device
+	informs    map[cwmp.Identity]*cwmp.Inform
+	parameters map[cwmp.Identity][]cwmp.ParameterValue
+	tasks      map[cwmp.Identity][]*Task
+	sessions   map[string]*SessionState
+}
+
+func (s *MemoryStore) init() {
+	if s.devices != nil {
+		return
+	}
+
+	s.devices = make(map[cwmp.Identity]*Device)
+	s.informs = make(map[cwmp.Identity]*cwmp.Inform)
+	s.parameters = make(map[cwmp.Identity][]cwmp.ParameterValue)
+	s.tasks = make(map[cwmp.Identity][]*Task)
+	s.sessions = make(map[string]*SessionState)
+}
+
+func (s *MemoryStore) Device(id cwmp.Identity) (*Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.devices[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	c := *d
+
+	return &c, nil
+}
+
+func (s *MemoryStore) Devices() ([]*Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	devices := make([]*Device, 0, len(s.devices))
+
+	for _, d := range s.devices {
+		c := *d
+		devices = append(devices, &c)
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].ID.String() < devices[j].ID.String()
+	})
+
+	return devices, nil
+}
+
+func (s *MemoryStore) PutDevice(d *Device) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.init()
+
+	c := *d
+	s.devices[d.ID] = &c
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteDevice(id cwmp.Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.devices[id]; !ok {
+		return ErrNotFound
+	}
+
+	delete(s.devices, id)
+	delete(s.informs, id)
+	delete(s.parameters, id)
+	delete(s.tasks, id)
+
+	return nil
+}
+
+func (s *MemoryStore) LastInform(id cwmp.Identity) (*cwmp.Inform, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.informs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	c := *m
+
+	return &c, nil
+}
+
+func (s *MemoryStore) PutInform(id cwmp.Identity, m *cwmp.Inform) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.init()
+
+	c := *m
+	c.Event = append(cwmp.EventList(nil), m.Event...)
+	c.ParameterList = append(cwmp.ParameterValueList(nil), m.ParameterList...)
+
+	s.informs[id] = &c
+
+	return nil
+}
+
+func (s *MemoryStore) Parameters(id cwmp.Identity) ([]cwmp.ParameterValue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]cwmp.ParameterValue(nil), s.parameters[id]...), nil
+}
+
+func (s *MemoryStore) PutParameters(id cwmp.Identity, params []cwmp.ParameterValue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.init()
+
+	s.parameters[id] = mergeParameters(s.parameters[id], params)
+
+	return nil
+}
+
+func (s *MemoryStore) Tasks(id cwmp.Identity) ([]*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := make([]*Task, 0, len(s.tasks[id]))
+
+	for _, t := range s.tasks[id] {
+		c := *t
+		tasks = append(tasks, &c)
+	}
+
+	return tasks, nil
+}
+
+func (s *MemoryStore) PutTask(t *Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.init()
+
+	c := *t
+	s.tasks[t.Device] = putTask(s.tasks[t.Device], &c)
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteTask(id cwmp.Identity, taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks, ok := deleteTask(s.tasks[id], taskID)
+	if !ok {
+		return ErrNotFound
+	}
+
+	s.tasks[id] = tasks
+
+	return nil
+}
+
+func (s *MemoryStore) Session(id string) (*SessionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	c := *state
+
+	return &c, nil
+}
+
+func (s *MemoryStore) Sessions() ([]*SessionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sessions := make([]*SessionState, 0, len(s.sessions))
+
+	for _, state := range s.sessions {
+		c := *state
+		sessions = append(sessions, &c)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].ID < sessions[j].ID
+	})
+
+	return sessions, nil
+}
+
+func (s *MemoryStore) PutSession(state *SessionState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.init()
+
+	c := *state
+	s.sessions[state.ID] = &c
+
+	return nil
+}
+
+func (s *MemoryStore) DeleteSession(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[id]; !ok {
+		return ErrNotFound
+	}
+
+	delete(s.sessions, id)
+
+	return nil
+}
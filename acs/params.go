@@ -0,0 +1,43 @@
+package acs
+
+import (
+	"strings"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+// NewGetParameterValues batches names into a single GetParameterValues
+// request. Duplicates and names already covered by a requested partial path
+// are dropped, otherwise the order is kept.
+func NewGetParameterValues(names ...string) *cwmp.GetParameterValues {
+	req := &cwmp.GetParameterValues{
+		ParameterNames: cwmp.StringList{},
+	}
+
+	seen := make(map[string]bool)
+
+	for _, name := range names {
+		if seen[name] || covered(name, names) {
+			continue
+		}
+
+		seen[name] = true
+		req.ParameterNames = append(req.ParameterNames, name)
+	}
+
+	return req
+}
+
+// covered reports whether name lies below one of the partial paths in names.
+// An empty name stands for the whole data model.
+func covered(name string, names []string) bool {
+	for _, n := range names {
+		partial := n == "" || strings.HasSuffix(n, ".")
+
+		if n != name && partial && strings.HasPrefix(name, n) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,42 @@
+package acs
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+func TestNewGetParameterValues(t *testing.T) {
+	got := NewGetParameterValues(
+		"Device.DeviceInfo.SoftwareVersion",
+		"Device.WiFi.SSID.1.SSID",
+		"Device.DeviceInfo.SoftwareVersion",
+		"Device.WiFi.",
+		"Device.ManagementServer.PeriodicInformInterval",
+	)
+
+	want := cwmp.StringList{
+		"Device.DeviceInfo.SoftwareVersion",
+		"Device.WiFi.",
+		"Device.ManagementServer.PeriodicInformInterval",
+	}
+
+	if !reflect.DeepEqual(want, got.ParameterNames) {
+		t.Fatalf("Not equal\nwant: %v\ngot:  %v", want, got.ParameterNames)
+	}
+}
+
+func TestNewGetParameterValuesRoot(t *testing.T) {
+	got := NewGetParameterValues("Device.DeviceInfo.", "", "Device.WiFi.SSID.1.SSID")
+
+	if !reflect.DeepEqual(cwmp.StringList{""}, got.ParameterNames) {
+		t.Fatalf("Expected only the root path got (%v)", got.ParameterNames)
+	}
+
+	got = NewGetParameterValues()
+
+	if got.ParameterNames == nil || len(got.ParameterNames) != 0 {
+		t.Fatalf("Expected an empty list got (%v)", got.ParameterNames)
+	}
+}
@@ -0,0 +1,507 @@
+package acs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+	"github.com/scottlangendyk/go-cwmp/soap"
+	"github.com/scottlangendyk/go-cwmp/xmlutil"
+)
+
+// DefaultAddr is the address ListenAndServe uses if Server.Addr is empty.
+const DefaultAddr = ":7547"
+
+// Server is an ACS. It is an http.Handler, so it can be mounted on any mux,
+// or it can listen by itself with ListenAndServe.
+//
+// The callbacks are called for the RPCs a CPE sends, with the identity of the
+// device that sent them. A callback returning a *cwmp.Fault answers with that
+// fault, any other error is logged and answered with a request denied fault.
+// Unset callbacks accept the request.
+type Server struct {
+	Addr      string
+	TLSConfig *tls.Config
+	CertFile  string
+	KeyFile   string
+
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	// Handler, if set, is what ListenAndServe serves instead of the Server
+	// itself, such as a mux with the Server and an UploadStore mounted.
+	Handler http.Handler
+
+	// Prefixes maps XML namespaces to the prefixes used for them in
+	// responses, in addition to the defaults. Array and value types name
+	// their type as cwmp:... or xsd:... in attribute values, so entries for
+	// the CWMP or XML Schema namespaces, or that use either prefix for
+	// another namespace, are ignored.
+	Prefixes map[string]string
+
+	// ErrorLog logs messages that can't be decoded or encoded. If nil,
+	// the log package's standard logger is used.
+	ErrorLog *log.Logger
+
+	OnInform                          func(device cwmp.Identity, m *cwmp.Inform) error
+	OnTransferComplete                func(device cwmp.Identity, m *cwmp.TransferComplete) error
+	OnAutonomousTransferComplete      func(device cwmp.Identity, m *cwmp.AutonomousTransferComplete) error
+	OnDUStateChangeComplete           func(device cwmp.Identity, m *cwmp.DUStateChangeComplete) error
+	OnAutonomousDUStateChangeComplete func(device cwmp.Identity, m *cwmp.AutonomousDUStateChangeComplete) error
+
+	// OnRequestDownload picks the file for a RequestDownload. The Download
+	// it returns, if any, is queued as a task for the device and sent in
+	// the same session.
+	OnRequestDownload func(device cwmp.Identity, m *cwmp.RequestDownload) (*cwmp.Download, error)
+
+	// OnFollowUp is called, before OnInform, for each request accepted in
+	// an earlier session that the Inform reports the outcome of: a
+	// ScheduleInform answered by its scheduled Inform or a FactoryReset
+	// answered by the BOOTSTRAP Inform. Those requests are remembered in
+	// memory for TaskRetention, so a restart or a follow-up arriving later
+	// than that goes unreported.
+	OnFollowUp func(device cwmp.Identity, m *cwmp.Inform, req cwmp.Message)
+
+	// OnKicked returns the URL the CPE should redirect the user's browser
+	// to. If unset the CPE is sent to the Next URL it asked for.
+	OnKicked func(device cwmp.Identity, m *cwmp.Kicked) (string, error)
+
+	// OnMethod is called with requests the server doesn't handle itself,
+	// such as registered X_<OUI>_ methods or a *cwmp.Unknown. It returns the
+	// response to send, or nil to answer with a method not supported fault.
+	OnMethod func(device cwmp.Identity, req interface{}) (interface{}, error)
+
+	// Store records devices, their Informs, parameters and tasks, and the
+	// sessions in progress. If nil, a MemoryStore is used.
+	Store Store
+
+	// OnTaskFinished is called when a task is done, faulted or expired.
+	OnTaskFinished func(t *Task)
+
+	// TaskRetention is how long finished tasks are kept in the Store. They
+	// are deleted when the device next starts a session after that. It
+	// defaults to DefaultTaskRetention.
+	TaskRetention time.Duration
+
+	// ConnectionRequester is used by DeviceClient calls to wake devices
+	// that aren't in a session.
+	ConnectionRequester ConnectionRequester
+
+	// SessionTimeout is how long a session may be idle if the CPE doesn't
+	// send a SessionTimeout header. It defaults to DefaultSessionTimeout.
+	SessionTimeout time.Duration
+
+	// HoldRequests asks CPEs to hold their own requests while the ACS has
+	// requests to send.
+	HoldRequests bool
+
+	// NextRequest returns the next request to send to device, or nil if
+	// there is none and the session can end.
+	NextRequest func(device cwmp.Identity) cwmp.Message
+
+	// OnResponse is called with the response to every request returned by
+	// NextRequest. If the CPE answered with a fault err is the *cwmp.Fault,
+	// if the session failed before it answered err says why.
+	OnResponse func(device cwmp.Identity, req, resp cwmp.Message, err error)
+
+	mu             sync.Mutex
+	memStore       *MemoryStore
+	pruneOnce      sync.Once
+	taskMu         sync.Mutex
+	waiters        map[string]chan *Task
+	followUps      followUps
+	duResults      duResults
+	sessions       map[string]*session
+	sessionsByAddr map[string]*session
+}
+
+var methodList = cwmp.StringList{
+	"Inform",
+	"GetRPCMethods",
+	"TransferComplete",
+	"AutonomousTransferComplete",
+	"DUStateChangeComplete",
+	"AutonomousDUStateChangeComplete",
+	"RequestDownload",
+	"Kicked",
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.ErrorLog != nil {
+		s.ErrorLog.Printf(format, args...)
+		return
+	}
+
+	log.Printf(format, args...)
+}
+
+// methodFault returns the fault to answer a request that failed with err.
+// Errors that aren't a *cwmp.Fault are logged and the CPE only sees a
+// standard Request denied fault.
+func (s *Server) methodFault(device cwmp.Identity, err error) *soap.Envelope {
+	var f *cwmp.Fault
+	if !errors.As(err, &f) {
+		s.logf("acs: %s: %v", device, err)
+		f = cwmp.NewFault(cwmp.ACSRequestDenied)
+	}
+
+	return &soap.Envelope{
+		Body: f.SOAPFault(),
+	}
+}
+
+// handleMessage handles one POST of a session, returning the session it
+// belongs to and the envelope to answer with, or nil to close the session.
+func (s *Server) handleMessage(r *http.Request) (*session, *soap.Envelope, error) {
+	defer r.Body.Close()
+
+	s.pruneOnce.Do(s.pruneSessions)
+	s.expireSessions(time.Now())
+
+	sess := s.session(r)
+	if sess != nil {
+		sess.mu.Lock()
+
+		// It may have timed out since it was looked up.
+		if sess.ended {
+			sess.mu.Unlock()
+			sess = nil
+		}
+	}
+
+	defer func() {
+		if sess != nil {
+			// The idle time runs from the answer to this POST.
+			if !sess.ended {
+				s.touch(sess)
+			}
+
+			sess.mu.Unlock()
+		}
+	}()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		if sess != nil {
+			s.endSession(sess, err)
+		}
+
+		return nil, nil, err
+	}
+
+	// An empty POST may be chunked or carry whitespace.
+	if len(bytes.TrimSpace(body)) == 0 {
+		if sess == nil {
+			return nil, nil, nil
+		}
+
+		if sess.outstanding != nil {
+			err := fmt.Errorf("%w: Empty POST while (%s) is outstanding", ErrUnexpectedResponse, sess.outstanding.Method().Name)
+			s.endSession(sess, err)
+			return nil, nil, err
+		}
+
+		sess.cpeDone = true
+
+		return sess, s.nextRequest(sess), nil
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(body))
+
+	msg, err := cwmp.Decode(d)
+	if err != nil {
+		if sess != nil {
+			s.endSession(sess, err)
+		}
+
+		return nil, nil, err
+	}
+
+	h, ok := msg.Header.(*cwmp.Header)
+	if !ok {
+		h = &cwmp.Header{}
+	}
+
+	if inform, ok := msg.Body.(*cwmp.Inform); ok {
+		if sess != nil {
+			s.endSession(sess, ErrSessionEnded)
+			sess.mu.Unlock()
+		}
+
+		device := inform.Identity()
+
+		if !s.inSession(device) {
+			s.tidyTasks(device)
+		}
+
+		sess = s.startSession(r, device, h)
+		sess.mu.Lock()
+	}
+
+	if sess == nil {
+		return nil, nil, ErrNoSession
+	}
+
+	var env *soap.Envelope
+
+	switch m := msg.Body.(type) {
+	case *soap.Fault:
+		env, err = s.handleResponse(sess, h, m)
+	case cwmp.Message:
+		if m.IsResponse() {
+			env, err = s.handleResponse(sess, h, m)
+			break
+		}
+
+		if sess.cpeDone {
+			err = fmt.Errorf("%w: (%s) after an empty POST", ErrUnexpectedRequest, m.Method().Name)
+			break
+		}
+
+		env = s.handleRequest(sess, h, m)
+	default:
+		if sess.cpeDone {
+			err = fmt.Errorf("%w: (%T) after an empty POST", ErrUnexpectedRequest, m)
+			break
+		}
+
+		env = s.handleRequest(sess, h, m)
+	}
+
+	if err != nil {
+		s.endSession(sess, err)
+		return nil, nil, err
+	}
+
+	return sess, env, nil
+}
+
+// handleRequest answers a request from the CPE.
+func (s *Server) handleRequest(sess *session, h *cwmp.Header, body interface{}) *soap.Envelope {
+	header := &cwmp.Header{
+		ID:        h.ID,
+		Namespace: sess.namespace,
+	}
+
+	device := sess.device
+
+	var resp interface{}
+	var err error
+
+	switch m := body.(type) {
+	case *cwmp.Inform:
+		version := cwmp.Negotiate(h)
+		if h.SupportedCWMPVersions != nil {
+			header.UseCWMPVersion = &version
+		}
+
+		sess.namespace, _ = cwmp.Namespace(version)
+		header.Namespace = sess.namespace
+
+		s.recordInform(device, m)
+		s.saveSession(sess)
+
+		for _, req := range s.followUps.match(device, m, time.Now(), s.taskRetention()) {
+			if s.OnFollowUp != nil {
+				s.OnFollowUp(device, m, req)
+			}
+		}
+
+		if s.OnInform != nil {
+			err = s.OnInform(device, m)
+		}
+
+		if s.holdRequests(sess) {
+			hold := true
+			header.HoldRequests = &hold
+		}
+
+		resp = &cwmp.InformResponse{}
+	case *cwmp.GetRPCMethods:
+		resp = &cwmp.GetRPCMethodsResponse{
+			MethodList: methodList,
+		}
+	case *cwmp.TransferComplete:
+		if s.OnTransferComplete != nil {
+			err = s.OnTransferComplete(device, m)
+		}
+
+		resp = &cwmp.TransferCompleteResponse{}
+	case *cwmp.AutonomousTransferComplete:
+		if s.OnAutonomousTransferComplete != nil {
+			err = s.OnAutonomousTransferComplete(device, m)
+		}
+
+		resp = &cwmp.AutonomousTransferCompleteResponse{}
+	case *cwmp.DUStateChangeComplete:
+		s.duResults.complete(device, m, time.Now(), s.taskRetention())
+
+		if s.OnDUStateChangeComplete != nil {
+			err = s.OnDUStateChangeComplete(device, m)
+		}
+
+		resp = &cwmp.DUStateChangeCompleteResponse{}
+	case *cwmp.AutonomousDUStateChangeComplete:
+		s.duResults.autonomousComplete(device, m, time.Now(), s.taskRetention())
+
+		if s.OnAutonomousDUStateChangeComplete != nil {
+			err = s.OnAutonomousDUStateChangeComplete(device, m)
+		}
+
+		resp = &cwmp.AutonomousDUStateChangeCompleteResponse{}
+	case *cwmp.RequestDownload:
+		var dl *cwmp.Download
+
+		if s.OnRequestDownload != nil {
+			dl, err = s.OnRequestDownload(device, m)
+		}
+
+		if err == nil && dl != nil {
+			_, err = s.Enqueue(device, dl, time.Time{})
+		}
+
+		resp = &cwmp.RequestDownloadResponse{}
+	case *cwmp.Kicked:
+		next := m.Next
+
+		if s.OnKicked != nil {
+			next, err = s.OnKicked(device, m)
+		}
+
+		resp = &cwmp.KickedResponse{NextURL: next}
+	default:
+		if s.OnMethod != nil {
+			resp, err = s.OnMethod(device, m)
+		}
+
+		if err == nil && resp == nil {
+			err = cwmp.NewFault(cwmp.ACSMethodNotSupported)
+		}
+	}
+
+	msg := &soap.Envelope{
+		Header: header,
+		Body:   resp,
+	}
+
+	if err != nil {
+		msg = s.methodFault(device, err)
+		msg.Header = header
+	}
+
+	return msg
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sess, msg, err := s.handleMessage(r)
+	if err != nil {
+		s.logf("acs: %s: %v", r.RemoteAddr, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if sess != nil && sess.isNew {
+		http.SetCookie(w, &http.Cookie{
+			Name:     SessionCookie,
+			Value:    sess.id,
+			Path:     "/",
+			HttpOnly: true,
+		})
+
+		sess.isNew = false
+	}
+
+	if msg == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	w.Header().Set("SOAPAction", "")
+
+	ns := cwmp.XMLSpace
+
+	h, ok := msg.Header.(*cwmp.Header)
+	if ok && h.Namespace != "" {
+		ns = h.Namespace
+	}
+
+	prefixes := map[string]string{
+		soap.XMLSpaceEnvelope:          "soapenv",
+		soap.XMLSpaceEncoding:          "soapenc",
+		ns:                             "cwmp",
+		xmlutil.XMLSpaceSchema:         "xsd",
+		xmlutil.XMLSpaceSchemaInstance: "xsi",
+	}
+
+	for space, prefix := range s.Prefixes {
+		if fixedPrefix(space, prefix) {
+			continue
+		}
+
+		prefixes[space] = prefix
+	}
+
+	p := xmlutil.NewPrefixer(w, prefixes)
+
+	e := cwmp.NewEncoder(p, ns)
+
+	err = e.Encode(msg)
+	if err != nil {
+		s.logf("acs: %s: %v", r.RemoteAddr, err)
+		return
+	}
+}
+
+// fixedPrefix reports whether a Prefixes entry would change the prefix of, or
+// reuse the prefix for, the namespaces that QName attribute values refer to.
+func fixedPrefix(space, prefix string) bool {
+	if _, ok := cwmp.Version(space); ok || space == xmlutil.XMLSpaceSchema {
+		return true
+	}
+
+	return prefix == "cwmp" || prefix == "xsd"
+}
+
+func (s *Server) httpServer() *http.Server {
+	addr := s.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+
+	var h http.Handler = s
+	if s.Handler != nil {
+		h = s.Handler
+	}
+
+	return &http.Server{
+		Addr:         addr,
+		Handler:      h,
+		TLSConfig:    s.TLSConfig,
+		ReadTimeout:  s.ReadTimeout,
+		WriteTimeout: s.WriteTimeout,
+		IdleTimeout:  s.IdleTimeout,
+		ErrorLog:     s.ErrorLog,
+	}
+}
+
+// ListenAndServe listens on Addr and serves CPE requests. It uses TLS if a
+// TLSConfig or certificate is configured.
+func (s *Server) ListenAndServe() error {
+	srv := s.httpServer()
+
+	if s.TLSConfig != nil || s.CertFile != "" {
+		return srv.ListenAndServeTLS(s.CertFile, s.KeyFile)
+	}
+
+	return srv.ListenAndServe()
+}
@@ -0,0 +1,339 @@
+package acs
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+	"github.com/scottlangendyk/go-cwmp/soap"
+	"github.com/scottlangendyk/go-cwmp/xmlutil"
+)
+
+const testInform = `<cwmp:Inform><DeviceId><OUI>E48D8C</OUI><ProductClass>hAP</ProductClass><SerialNumber>1</SerialNumber></DeviceId></cwmp:Inform>`
+
+var testDevice = cwmp.Identity{OUI: "E48D8C", ProductClass: "hAP", SerialNumber: "1"}
+
+func post(t *testing.T, s *Server, body string) *soap.Envelope {
+	input := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:cwmp="urn:dslforum-org:cwmp-1-0"><soapenv:Body>` + body + `</soapenv:Body></soapenv:Envelope>`
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader(input))
+
+	_, msg, err := s.handleMessage(r)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	return msg
+}
+
+func assertFault(t *testing.T, msg *soap.Envelope, code uint) *cwmp.Fault {
+	sf, ok := msg.Body.(*soap.Fault)
+	if !ok {
+		t.Fatalf("Expected Fault got (%T)", msg.Body)
+	}
+
+	f, ok := cwmp.FromSOAPFault(sf)
+	if !ok || f.Code != code {
+		t.Fatalf("Expected (%d) got (%v)", code, sf.Detail)
+	}
+
+	return f
+}
+
+func TestServerNegotiatesVersion(t *testing.T) {
+	input := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:cwmp="urn:dslforum-org:cwmp-1-4"><soapenv:Header><cwmp:ID soapenv:mustUnderstand="1">42</cwmp:ID><cwmp:SupportedCWMPVersions>1.0,1.2,1.4</cwmp:SupportedCWMPVersions></soapenv:Header><soapenv:Body><cwmp:Inform><DeviceId><OUI>E48D8C</OUI><ProductClass>hAP</ProductClass><SerialNumber>2</SerialNumber></DeviceId></cwmp:Inform></soapenv:Body></soapenv:Envelope>`
+
+	w := httptest.NewRecorder()
+
+	s := &Server{}
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(input)))
+
+	got := w.Body.String()
+
+	for _, want := range []string{
+		`xmlns:cwmp="urn:dslforum-org:cwmp-1-4"`,
+		`<cwmp:ID soapenv:mustUnderstand="1">42</cwmp:ID>`,
+		`<cwmp:UseCWMPVersion soapenv:mustUnderstand="1">1.4</cwmp:UseCWMPVersion>`,
+		`<cwmp:InformResponse>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected (%s) in (%s)", want, got)
+		}
+	}
+
+	if strings.Contains(got, "cwmp-1-0") {
+		t.Errorf("Unexpected cwmp-1-0 namespace in (%s)", got)
+	}
+}
+
+func TestServerPrefixes(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	s := &Server{
+		Prefixes: map[string]string{
+			soap.XMLSpaceEnvelope:  "SOAP-ENV",
+			cwmp.XMLSpace:          "cwmp10",
+			xmlutil.XMLSpaceSchema: "xs",
+			"urn:example":          "xsd",
+		},
+	}
+
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:cwmp="urn:dslforum-org:cwmp-1-0"><soapenv:Body>`+testInform+`</soapenv:Body></soapenv:Envelope>`)))
+
+	got := w.Body.String()
+
+	if !strings.HasPrefix(got, `<SOAP-ENV:Envelope `) || !strings.Contains(got, `xmlns:SOAP-ENV="http://schemas.xmlsoap.org/soap/envelope/"`) {
+		t.Fatalf("Unexpected response (%s)", got)
+	}
+
+	// arrayType and xsi:type values are written as cwmp:... and xsd:...
+	for _, want := range []string{`xmlns:cwmp="urn:dslforum-org:cwmp-1-0"`, `xmlns:xsd="http://www.w3.org/2001/XMLSchema"`} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("Expected (%s) in (%s)", want, got)
+		}
+	}
+
+	if strings.Contains(got, "urn:example") {
+		t.Fatalf("Unexpected namespace in (%s)", got)
+	}
+}
+
+func TestServerEmptyRequest(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	s := &Server{}
+	s.ServeHTTP(w, httptest.NewRequest("POST", "/", nil))
+
+	if w.Code != 204 {
+		t.Fatalf("Expected (204) got (%d)", w.Code)
+	}
+}
+
+func TestServerInform(t *testing.T) {
+	var got cwmp.Identity
+
+	s := &Server{
+		OnInform: func(device cwmp.Identity, m *cwmp.Inform) error {
+			got = device
+			return nil
+		},
+	}
+
+	msg := post(t, s, testInform)
+
+	if _, ok := msg.Body.(*cwmp.InformResponse); !ok {
+		t.Fatalf("Expected InformResponse got (%T)", msg.Body)
+	}
+
+	if got != testDevice {
+		t.Fatalf("Expected (%s) got (%s)", testDevice, got)
+	}
+
+	s.OnInform = func(device cwmp.Identity, m *cwmp.Inform) error {
+		return cwmp.NewFault(cwmp.ACSRetryRequest)
+	}
+
+	assertFault(t, post(t, s, testInform), cwmp.ACSRetryRequest)
+}
+
+func TestServerRequestDownload(t *testing.T) {
+	var device cwmp.Identity
+	var got *cwmp.RequestDownload
+
+	dl := &cwmp.Download{CommandKey: "fw", FileType: "1 Firmware Upgrade Image", URL: "http://files/fw.npk"}
+
+	s := &Server{
+		OnRequestDownload: func(d cwmp.Identity, r *cwmp.RequestDownload) (*cwmp.Download, error) {
+			device = d
+			got = r
+			return dl, nil
+		},
+	}
+
+	post(t, s, testInform)
+
+	msg := post(t, s, `<cwmp:RequestDownload><FileType>1 Firmware Upgrade Image</FileType><FileTypeArg><ArgStruct><Name>Version</Name><Value>6.47</Value></ArgStruct></FileTypeArg></cwmp:RequestDownload>`)
+
+	if _, ok := msg.Body.(*cwmp.RequestDownloadResponse); !ok {
+		t.Fatalf("Expected RequestDownloadResponse got (%T)", msg.Body)
+	}
+
+	if got == nil || len(got.FileTypeArg) != 1 || got.FileTypeArg[0].Value != "6.47" {
+		t.Fatalf("Unexpected request (%v)", got)
+	}
+
+	if device != testDevice {
+		t.Fatalf("Expected (%s) got (%s)", testDevice, device)
+	}
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertRequest(t, msg, "1", dl)
+}
+
+func TestServerKicked(t *testing.T) {
+	s := &Server{}
+
+	post(t, s, testInform)
+
+	msg := post(t, s, `<cwmp:Kicked><Command>register</Command><Referer></Referer><Arg></Arg><Next>http://portal.example.com/next</Next></cwmp:Kicked>`)
+
+	res, ok := msg.Body.(*cwmp.KickedResponse)
+	if !ok || res.NextURL != "http://portal.example.com/next" {
+		t.Fatalf("Unexpected response (%v)", msg.Body)
+	}
+
+	s.OnKicked = func(device cwmp.Identity, k *cwmp.Kicked) (string, error) {
+		return "http://portal.example.com/" + k.Command, nil
+	}
+
+	msg = post(t, s, `<cwmp:Kicked><Command>register</Command><Referer></Referer><Arg></Arg><Next></Next></cwmp:Kicked>`)
+
+	res, ok = msg.Body.(*cwmp.KickedResponse)
+	if !ok {
+		t.Fatalf("Expected KickedResponse got (%T)", msg.Body)
+	}
+
+	if res.NextURL != "http://portal.example.com/register" {
+		t.Fatalf("Expected (http://portal.example.com/register) got (%s)", res.NextURL)
+	}
+
+	var logged bytes.Buffer
+	s.ErrorLog = log.New(&logged, "", 0)
+
+	s.OnKicked = func(device cwmp.Identity, k *cwmp.Kicked) (string, error) {
+		return "", errors.New("Unknown command")
+	}
+
+	f := assertFault(t, post(t, s, `<cwmp:Kicked><Command>unknown</Command></cwmp:Kicked>`), cwmp.ACSRequestDenied)
+
+	if f.String != cwmp.FaultString(cwmp.ACSRequestDenied) {
+		t.Fatalf("Expected (%s) got (%s)", cwmp.FaultString(cwmp.ACSRequestDenied), f.String)
+	}
+
+	if !strings.Contains(logged.String(), "Unknown command") {
+		t.Fatalf("Expected the error to be logged got (%s)", logged.String())
+	}
+}
+
+func TestServerCompletions(t *testing.T) {
+	var calls []string
+
+	s := &Server{
+		OnTransferComplete: func(device cwmp.Identity, m *cwmp.TransferComplete) error {
+			calls = append(calls, "TransferComplete "+m.CommandKey)
+			return nil
+		},
+		OnAutonomousTransferComplete: func(device cwmp.Identity, m *cwmp.AutonomousTransferComplete) error {
+			calls = append(calls, "AutonomousTransferComplete "+m.TransferURL)
+			return nil
+		},
+		OnDUStateChangeComplete: func(device cwmp.Identity, m *cwmp.DUStateChangeComplete) error {
+			calls = append(calls, "DUStateChangeComplete "+m.CommandKey)
+			return nil
+		},
+		OnAutonomousDUStateChangeComplete: func(device cwmp.Identity, m *cwmp.AutonomousDUStateChangeComplete) error {
+			calls = append(calls, "AutonomousDUStateChangeComplete")
+			return nil
+		},
+	}
+
+	for _, tt := range []struct {
+		body string
+		want interface{}
+	}{
+		{`<cwmp:TransferComplete><CommandKey>fw</CommandKey></cwmp:TransferComplete>`, &cwmp.TransferCompleteResponse{}},
+		{`<cwmp:AutonomousTransferComplete><TransferURL>http://files</TransferURL></cwmp:AutonomousTransferComplete>`, &cwmp.AutonomousTransferCompleteResponse{}},
+		{`<cwmp:DUStateChangeComplete><CommandKey>apps</CommandKey></cwmp:DUStateChangeComplete>`, &cwmp.DUStateChangeCompleteResponse{}},
+		{`<cwmp:AutonomousDUStateChangeComplete></cwmp:AutonomousDUStateChangeComplete>`, &cwmp.AutonomousDUStateChangeCompleteResponse{}},
+	} {
+		post(t, s, testInform)
+
+		msg := post(t, s, tt.body)
+
+		if reflect.TypeOf(msg.Body) != reflect.TypeOf(tt.want) {
+			t.Fatalf("Expected (%T) got (%T)", tt.want, msg.Body)
+		}
+	}
+
+	want := []string{
+		"TransferComplete fw",
+		"AutonomousTransferComplete http://files",
+		"DUStateChangeComplete apps",
+		"AutonomousDUStateChangeComplete",
+	}
+
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected (%v) got (%v)", want, calls)
+	}
+}
+
+func TestServerGetRPCMethods(t *testing.T) {
+	s := &Server{}
+
+	post(t, s, testInform)
+
+	msg := post(t, s, `<cwmp:GetRPCMethods></cwmp:GetRPCMethods>`)
+
+	res, ok := msg.Body.(*cwmp.GetRPCMethodsResponse)
+	if !ok {
+		t.Fatalf("Expected GetRPCMethodsResponse got (%T)", msg.Body)
+	}
+
+	if len(res.MethodList) != 8 || res.MethodList[0] != "Inform" {
+		t.Fatalf("Unexpected methods (%v)", res.MethodList)
+	}
+}
+
+func TestServerVendorMethod(t *testing.T) {
+	s := &Server{}
+
+	post(t, s, testInform)
+
+	assertFault(t, post(t, s, `<cwmp:X_000000_Foo><Mode>hard</Mode></cwmp:X_000000_Foo>`), cwmp.ACSMethodNotSupported)
+
+	s.OnMethod = func(device cwmp.Identity, req interface{}) (interface{}, error) {
+		u, ok := req.(*cwmp.Unknown)
+		if !ok || u.XMLName.Local != "X_000000_Foo" {
+			return nil, nil
+		}
+
+		return &cwmp.Unknown{XMLName: xml.Name{Space: cwmp.XMLSpace, Local: "X_000000_FooResponse"}}, nil
+	}
+
+	msg := post(t, s, `<cwmp:X_000000_Foo><Mode>hard</Mode></cwmp:X_000000_Foo>`)
+
+	res, ok := msg.Body.(*cwmp.Unknown)
+	if !ok || res.XMLName.Local != "X_000000_FooResponse" {
+		t.Fatalf("Unexpected response (%v)", msg.Body)
+	}
+
+	assertFault(t, post(t, s, `<cwmp:X_000000_Bar></cwmp:X_000000_Bar>`), cwmp.ACSMethodNotSupported)
+}
+
+func TestServerHTTPServer(t *testing.T) {
+	s := &Server{ReadTimeout: time.Second}
+
+	srv := s.httpServer()
+	if srv.Addr != DefaultAddr || srv.Handler != s || srv.ReadTimeout != time.Second {
+		t.Fatalf("Unexpected server (%v) (%v) (%v)", srv.Addr, srv.Handler, srv.ReadTimeout)
+	}
+
+	mux := http.NewServeMux()
+	s.Handler = mux
+
+	if s.httpServer().Handler != mux {
+		t.Fatal("Expected the Handler to be served")
+	}
+}
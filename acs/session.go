@@ -0,0 +1,381 @@
+package acs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+	"github.com/scottlangendyk/go-cwmp/soap"
+)
+
+// SessionCookie is the name of the cookie that ties the POSTs of a session
+// together. CPEs that don't return cookies are tracked by remote address.
+const SessionCookie = "cwmp-session"
+
+// DefaultSessionTimeout is how long a session may be idle if the CPE doesn't
+// send a SessionTimeout header.
+const DefaultSessionTimeout = 30 * time.Second
+
+var (
+	ErrNoSession          = errors.New("acs: Message outside of a session")
+	ErrSessionTimeout     = errors.New("acs: Session timed out")
+	ErrSessionEnded       = errors.New("acs: Session ended before the request was answered")
+	ErrUnexpectedResponse = errors.New("acs: Unexpected response")
+	ErrUnexpectedRequest  = errors.New("acs: Unexpected request")
+)
+
+// session is the state of one CWMP session: the Inform, the CPE's requests,
+// an empty POST, then the ACS's requests until there are none left and the
+// session is closed with a 204.
+type session struct {
+	mu sync.Mutex
+
+	id        string
+	addr      string
+	isNew     bool
+	device    cwmp.Identity
+	namespace string
+	timeout   time.Duration
+	started   time.Time
+
+	// lastSeen and closing are guarded by the server's mu. closing is set
+	// once the ACS has run out of requests to send. timer ends the session
+	// once it has been idle for timeout, even if no other POST arrives.
+	lastSeen time.Time
+	closing  bool
+	timer    *time.Timer
+	ended    bool
+
+	// cpeDone is set once the CPE sent an empty POST, after which it
+	// may only send responses.
+	cpeDone bool
+
+	seq             int
+	outstanding     cwmp.Message
+	outstandingID   string
+	outstandingTask *Task
+
+	// next is the request after outstanding, fetched early when holding
+	// the CPE's requests to know whether to keep holding them.
+	next     cwmp.Message
+	nextTask *Task
+}
+
+func newID() string {
+	b := make([]byte, 16)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func (s *Server) sessionTimeout(h *cwmp.Header) time.Duration {
+	if h.SessionTimeout != nil && *h.SessionTimeout > 0 {
+		return time.Duration(*h.SessionTimeout) * time.Second
+	}
+
+	if s.SessionTimeout > 0 {
+		return s.SessionTimeout
+	}
+
+	return DefaultSessionTimeout
+}
+
+// session returns the session r belongs to, by cookie or else by remote
+// address, and marks it as active.
+func (s *Server) session(r *http.Request) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sess *session
+
+	c, err := r.Cookie(SessionCookie)
+	if err == nil {
+		sess = s.sessions[c.Value]
+	}
+
+	if sess == nil {
+		sess = s.sessionsByAddr[r.RemoteAddr]
+	}
+
+	if sess != nil {
+		s.touchLocked(sess)
+	}
+
+	return sess
+}
+
+// touch marks sess as active, restarting its timeout.
+func (s *Server) touch(sess *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.touchLocked(sess)
+}
+
+func (s *Server) touchLocked(sess *session) {
+	sess.lastSeen = time.Now()
+	sess.timer.Reset(sess.timeout)
+}
+
+func (s *Server) startSession(r *http.Request, device cwmp.Identity, h *cwmp.Header) *session {
+	now := time.Now()
+
+	sess := &session{
+		id:       newID(),
+		addr:     r.RemoteAddr,
+		isNew:    true,
+		device:   device,
+		timeout:  s.sessionTimeout(h),
+		started:  now,
+		lastSeen: now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.sessions == nil {
+		s.sessions = make(map[string]*session)
+		s.sessionsByAddr = make(map[string]*session)
+	}
+
+	s.sessions[sess.id] = sess
+	s.sessionsByAddr[sess.addr] = sess
+
+	sess.timer = time.AfterFunc(sess.timeout, func() {
+		s.expireSession(sess, time.Now())
+	})
+
+	return sess
+}
+
+// endSession forgets sess, failing its outstanding request, and the next one
+// if it was fetched already, with err. The caller holds sess.mu.
+func (s *Server) endSession(sess *session, err error) {
+	sess.ended = true
+	sess.timer.Stop()
+
+	s.mu.Lock()
+
+	delete(s.sessions, sess.id)
+
+	if s.sessionsByAddr[sess.addr] == sess {
+		delete(s.sessionsByAddr, sess.addr)
+	}
+
+	s.mu.Unlock()
+
+	s.forgetSession(sess)
+
+	if err == nil {
+		err = ErrSessionEnded
+	}
+
+	req, task := sess.outstanding, sess.outstandingTask
+	sess.outstanding, sess.outstandingTask = nil, nil
+
+	if task != nil {
+		s.requeueTask(task)
+	} else if req != nil && s.OnResponse != nil {
+		s.OnResponse(sess.device, req, nil, err)
+	}
+
+	// A prefetched task was never sent, so it is still pending.
+	req, task = sess.next, sess.nextTask
+	sess.next, sess.nextTask = nil, nil
+
+	if task == nil && req != nil && s.OnResponse != nil {
+		s.OnResponse(sess.device, req, nil, err)
+	}
+}
+
+// expireSessions ends the sessions that have been idle for too long. Each
+// session's timer does the same, this catches up with any that are late.
+func (s *Server) expireSessions(now time.Time) {
+	var expired []*session
+
+	s.mu.Lock()
+
+	for _, sess := range s.sessions {
+		if now.Sub(sess.lastSeen) > sess.timeout {
+			expired = append(expired, sess)
+		}
+	}
+
+	s.mu.Unlock()
+
+	for _, sess := range expired {
+		s.expireSession(sess, now)
+	}
+}
+
+// expireSession ends sess if it has been idle for longer than its timeout
+// and hasn't ended already.
+func (s *Server) expireSession(sess *session, now time.Time) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.ended {
+		return
+	}
+
+	// A POST may have arrived while waiting for the lock.
+	s.mu.Lock()
+	idle := now.Sub(sess.lastSeen)
+	s.mu.Unlock()
+
+	if idle <= sess.timeout {
+		return
+	}
+
+	s.endSession(sess, ErrSessionTimeout)
+}
+
+// fetchRequest returns the next request for device, from its queued tasks
+// first and then from NextRequest.
+func (s *Server) fetchRequest(device cwmp.Identity) (cwmp.Message, *Task) {
+	task := s.nextTask(device)
+	if task != nil {
+		return task.Request, task
+	}
+
+	if s.NextRequest == nil {
+		return nil, nil
+	}
+
+	return s.NextRequest(device), nil
+}
+
+// holdRequests reports whether the CPE should be told to hold its requests
+// because the ACS has one to send first.
+func (s *Server) holdRequests(sess *session) bool {
+	if !s.HoldRequests {
+		return false
+	}
+
+	if sess.next == nil {
+		sess.next, sess.nextTask = s.fetchRequest(sess.device)
+	}
+
+	return sess.next != nil
+}
+
+// nextRequest returns the next ACS request for the session, or nil and ends
+// the session if there are none.
+func (s *Server) nextRequest(sess *session) *soap.Envelope {
+	req, task := sess.next, sess.nextTask
+	sess.next, sess.nextTask = nil, nil
+
+	if req == nil {
+		req, task = s.fetchRequest(sess.device)
+	}
+
+	// A task may have been cancelled since it was fetched.
+	for task != nil && !s.sendTask(task) {
+		req, task = s.fetchRequest(sess.device)
+	}
+
+	if req == nil {
+		// DeviceClient calls from here on wake the device instead of
+		// waiting for this session, so look once more for any queued
+		// before that.
+		s.setClosing(sess, true)
+
+		task = s.nextTask(sess.device)
+		for task != nil && !s.sendTask(task) {
+			task = s.nextTask(sess.device)
+		}
+
+		if task == nil {
+			s.endSession(sess, nil)
+			return nil
+		}
+
+		s.setClosing(sess, false)
+		req = task.Request
+	}
+
+	sess.seq++
+
+	id := strconv.Itoa(sess.seq)
+
+	sess.outstanding = req
+	sess.outstandingID = id
+	sess.outstandingTask = task
+
+	header := &cwmp.Header{
+		ID:        &id,
+		Namespace: sess.namespace,
+	}
+
+	if s.holdRequests(sess) {
+		hold := true
+		header.HoldRequests = &hold
+	}
+
+	return &soap.Envelope{
+		Header: header,
+		Body:   req,
+	}
+}
+
+func (s *Server) setClosing(sess *session, closing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess.closing = closing
+}
+
+// handleResponse matches a response from the CPE to the outstanding request
+// and moves on to the next one.
+func (s *Server) handleResponse(sess *session, h *cwmp.Header, body interface{}) (*soap.Envelope, error) {
+	req := sess.outstanding
+	if req == nil {
+		return nil, fmt.Errorf("%w (%T)", ErrUnexpectedResponse, body)
+	}
+
+	if h.ID != nil && *h.ID != sess.outstandingID {
+		return nil, fmt.Errorf("%w: ID (%s) doesn't match (%s)", ErrUnexpectedResponse, *h.ID, sess.outstandingID)
+	}
+
+	err := cwmp.MatchResponse(req, body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnexpectedResponse, err)
+	}
+
+	task := sess.outstandingTask
+
+	sess.outstanding = nil
+	sess.outstandingTask = nil
+
+	var resp cwmp.Message
+
+	switch b := body.(type) {
+	case *soap.Fault:
+		f, _ := cwmp.FromSOAPFault(b)
+		err = f
+	case cwmp.Message:
+		resp = b
+	}
+
+	if err == nil && hasFollowUp(req) {
+		s.followUps.add(sess.device, req, time.Now(), s.taskRetention())
+	}
+
+	if task != nil {
+		s.completeTask(task, resp, err)
+	} else if s.OnResponse != nil {
+		s.OnResponse(sess.device, req, resp, err)
+	}
+
+	return s.nextRequest(sess), nil
+}
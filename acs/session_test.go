@@ -0,0 +1,370 @@
+package acs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+	"github.com/scottlangendyk/go-cwmp/soap"
+)
+
+const testFault = `<soapenv:Fault><faultcode>Client</faultcode><faultstring>CWMP fault</faultstring><detail><cwmp:Fault><FaultCode>9001</FaultCode><FaultString>Request denied</FaultString></cwmp:Fault></detail></soapenv:Fault>`
+
+func newRequest(id, body string) *http.Request {
+	if body == "" {
+		return httptest.NewRequest("POST", "/", nil)
+	}
+
+	header := ""
+	if id != "" {
+		header = `<soapenv:Header><cwmp:ID soapenv:mustUnderstand="1">` + id + `</cwmp:ID></soapenv:Header>`
+	}
+
+	input := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:cwmp="urn:dslforum-org:cwmp-1-0">` + header + `<soapenv:Body>` + body + `</soapenv:Body></soapenv:Envelope>`
+
+	return httptest.NewRequest("POST", "/", strings.NewReader(input))
+}
+
+type response struct {
+	req  cwmp.Message
+	resp cwmp.Message
+	err  error
+}
+
+// queue returns a server that sends reqs in order and records the responses.
+func queue(reqs ...cwmp.Message) (*Server, *[]response) {
+	var got []response
+
+	s := &Server{
+		NextRequest: func(device cwmp.Identity) cwmp.Message {
+			if len(reqs) == 0 {
+				return nil
+			}
+
+			req := reqs[0]
+			reqs = reqs[1:]
+
+			return req
+		},
+		OnResponse: func(device cwmp.Identity, req, resp cwmp.Message, err error) {
+			got = append(got, response{req, resp, err})
+		},
+	}
+
+	return s, &got
+}
+
+func assertRequest(t *testing.T, msg *soap.Envelope, id string, want cwmp.Message) {
+	if msg == nil {
+		t.Fatalf("Expected (%T) got nil", want)
+	}
+
+	if msg.Body != want {
+		t.Fatalf("Expected (%T) got (%T)", want, msg.Body)
+	}
+
+	h := msg.Header.(*cwmp.Header)
+	if h.ID == nil || *h.ID != id {
+		t.Fatalf("Expected ID (%s) got (%v)", id, h.ID)
+	}
+}
+
+func TestSessionCookie(t *testing.T) {
+	s := &Server{}
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, newRequest("", testInform))
+
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == SessionCookie {
+			cookie = c
+		}
+	}
+
+	if cookie == nil || cookie.Value == "" {
+		t.Fatalf("Expected session cookie got (%v)", w.Result().Cookies())
+	}
+
+	r := newRequest("", `<cwmp:GetRPCMethods></cwmp:GetRPCMethods>`)
+	r.RemoteAddr = "192.0.2.2:1234"
+	r.AddCookie(cookie)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	if w.Code != 200 || len(w.Result().Cookies()) != 0 {
+		t.Fatalf("Unexpected response (%d) (%v)", w.Code, w.Result().Cookies())
+	}
+
+	r = newRequest("", "")
+	r.AddCookie(cookie)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+
+	if w.Code != 204 {
+		t.Fatalf("Expected (204) got (%d)", w.Code)
+	}
+
+	r = newRequest("", `<cwmp:GetRPCMethods></cwmp:GetRPCMethods>`)
+	r.AddCookie(cookie)
+
+	_, _, err := s.handleMessage(r)
+	if err != ErrNoSession {
+		t.Fatalf("Expected (%v) got (%v)", ErrNoSession, err)
+	}
+}
+
+func TestSessionRequests(t *testing.T) {
+	get := &cwmp.GetParameterValues{ParameterNames: cwmp.StringList{"Device.DeviceInfo.SoftwareVersion"}}
+	reboot := &cwmp.Reboot{CommandKey: "reboot"}
+
+	s, got := queue(get, reboot)
+
+	post(t, s, testInform)
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertRequest(t, msg, "1", get)
+
+	_, msg, err = s.handleMessage(newRequest("1", `<cwmp:GetParameterValuesResponse><ParameterList><ParameterValueStruct><Name>Device.DeviceInfo.SoftwareVersion</Name><Value>7.1</Value></ParameterValueStruct></ParameterList></cwmp:GetParameterValuesResponse>`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertRequest(t, msg, "2", reboot)
+
+	_, msg, err = s.handleMessage(newRequest("2", testFault))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if msg != nil {
+		t.Fatalf("Expected session to end got (%T)", msg.Body)
+	}
+
+	if len(*got) != 2 {
+		t.Fatalf("Expected 2 responses got (%d)", len(*got))
+	}
+
+	res, ok := (*got)[0].resp.(*cwmp.GetParameterValuesResponse)
+	if (*got)[0].req != get || !ok || len(res.ParameterList) != 1 || res.ParameterList[0].Value != "7.1" {
+		t.Fatalf("Unexpected response (%v)", (*got)[0])
+	}
+
+	if (*got)[1].req != reboot || !errors.Is((*got)[1].err, cwmp.NewFault(cwmp.CPERequestDenied)) {
+		t.Fatalf("Unexpected response (%v)", (*got)[1])
+	}
+}
+
+func TestSessionUnexpectedResponse(t *testing.T) {
+	for _, tt := range []struct {
+		id   string
+		body string
+	}{
+		{"2", `<cwmp:RebootResponse></cwmp:RebootResponse>`},
+		{"1", `<cwmp:GetParameterValuesResponse></cwmp:GetParameterValuesResponse>`},
+	} {
+		s, got := queue(&cwmp.Reboot{})
+
+		post(t, s, testInform)
+
+		_, _, err := s.handleMessage(newRequest("", ""))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		_, _, err = s.handleMessage(newRequest(tt.id, tt.body))
+		if !errors.Is(err, ErrUnexpectedResponse) {
+			t.Fatalf("Expected (%v) got (%v)", ErrUnexpectedResponse, err)
+		}
+
+		if len(*got) != 1 || (*got)[0].err != err {
+			t.Fatalf("Unexpected responses (%v)", *got)
+		}
+	}
+}
+
+func TestSessionUnexpectedRequest(t *testing.T) {
+	s, _ := queue()
+
+	post(t, s, testInform)
+
+	_, _, err := s.handleMessage(newRequest("", `<cwmp:RebootResponse></cwmp:RebootResponse>`))
+	if !errors.Is(err, ErrUnexpectedResponse) {
+		t.Fatalf("Expected (%v) got (%v)", ErrUnexpectedResponse, err)
+	}
+
+	s, _ = queue(&cwmp.Reboot{})
+
+	post(t, s, testInform)
+
+	_, _, err = s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	_, _, err = s.handleMessage(newRequest("", `<cwmp:GetRPCMethods></cwmp:GetRPCMethods>`))
+	if !errors.Is(err, ErrUnexpectedRequest) {
+		t.Fatalf("Expected (%v) got (%v)", ErrUnexpectedRequest, err)
+	}
+}
+
+func TestSessionHoldRequests(t *testing.T) {
+	reboot := &cwmp.Reboot{}
+
+	s, _ := queue(reboot)
+	s.HoldRequests = true
+
+	msg := post(t, s, testInform)
+
+	h := msg.Header.(*cwmp.Header)
+	if h.HoldRequests == nil || !*h.HoldRequests {
+		t.Fatalf("Expected HoldRequests got (%v)", h.HoldRequests)
+	}
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertRequest(t, msg, "1", reboot)
+
+	if h := msg.Header.(*cwmp.Header); h.HoldRequests != nil {
+		t.Fatalf("Unexpected HoldRequests (%v)", *h.HoldRequests)
+	}
+}
+
+func TestSessionTimeout(t *testing.T) {
+	s, got := queue(&cwmp.Reboot{})
+
+	post(t, s, testInform)
+
+	_, _, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	s.expireSessions(time.Now().Add(DefaultSessionTimeout + time.Second))
+
+	if len(*got) != 1 || (*got)[0].err != ErrSessionTimeout {
+		t.Fatalf("Unexpected responses (%v)", *got)
+	}
+
+	_, _, err = s.handleMessage(newRequest("1", `<cwmp:RebootResponse></cwmp:RebootResponse>`))
+	if err != ErrNoSession {
+		t.Fatalf("Expected (%v) got (%v)", ErrNoSession, err)
+	}
+}
+
+func TestSessionTimeoutIdle(t *testing.T) {
+	errs := make(chan error, 1)
+
+	reboot := &cwmp.Reboot{}
+	sent := false
+
+	s := &Server{
+		SessionTimeout: 50 * time.Millisecond,
+		NextRequest: func(device cwmp.Identity) cwmp.Message {
+			if sent {
+				return nil
+			}
+
+			sent = true
+			return reboot
+		},
+		OnResponse: func(device cwmp.Identity, req, resp cwmp.Message, err error) {
+			errs <- err
+		},
+	}
+
+	post(t, s, testInform)
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertRequest(t, msg, "1", reboot)
+
+	// No other POST arrives to notice the session is over.
+	select {
+	case err := <-errs:
+		if err != ErrSessionTimeout {
+			t.Fatalf("Expected (%v) got (%v)", ErrSessionTimeout, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the session to time out")
+	}
+
+	s.mu.Lock()
+	n := len(s.sessions)
+	s.mu.Unlock()
+
+	if n != 0 {
+		t.Fatalf("Expected no sessions got (%d)", n)
+	}
+}
+
+func TestSessionEmptyPostWhileOutstanding(t *testing.T) {
+	s, got := queue(&cwmp.Reboot{})
+
+	post(t, s, testInform)
+
+	_, _, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	_, _, err = s.handleMessage(newRequest("", ""))
+	if !errors.Is(err, ErrUnexpectedResponse) {
+		t.Fatalf("Expected (%v) got (%v)", ErrUnexpectedResponse, err)
+	}
+
+	if len(*got) != 1 || (*got)[0].err != err {
+		t.Fatalf("Unexpected responses (%v)", *got)
+	}
+}
+
+func TestSessionPrefetchedRequestFailed(t *testing.T) {
+	reboot := &cwmp.Reboot{}
+
+	s, got := queue(reboot)
+	s.HoldRequests = true
+
+	post(t, s, testInform)
+
+	s.expireSessions(time.Now().Add(DefaultSessionTimeout + time.Second))
+
+	if len(*got) != 1 || (*got)[0].req != reboot || (*got)[0].err != ErrSessionTimeout {
+		t.Fatalf("Unexpected responses (%v)", *got)
+	}
+}
+
+func TestSessionEmptyBody(t *testing.T) {
+	for _, body := range []string{"", " \r\n"} {
+		reboot := &cwmp.Reboot{}
+
+		s, _ := queue(reboot)
+
+		post(t, s, testInform)
+
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.ContentLength = -1
+
+		_, msg, err := s.handleMessage(r)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		assertRequest(t, msg, "1", reboot)
+	}
+}
@@ -0,0 +1,224 @@
+package acs
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+// ErrNotFound is returned by a Store for a record that doesn't exist.
+var ErrNotFound = errors.New("acs: Not found")
+
+// Device is what the ACS knows about a device from its Informs.
+type Device struct {
+	ID        cwmp.Identity
+	Summary   cwmp.DeviceSummary
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// SessionState is the part of a session that outlives the connection.
+type SessionState struct {
+	ID        string
+	Device    cwmp.Identity
+	Namespace string
+	Started   time.Time
+	LastSeen  time.Time
+}
+
+type DeviceStore interface {
+	Device(id cwmp.Identity) (*Device, error)
+	Devices() ([]*Device, error)
+	PutDevice(d *Device) error
+
+	// DeleteDevice forgets the device along with its Inform, parameters
+	// and tasks.
+	DeleteDevice(id cwmp.Identity) error
+}
+
+type InformStore interface {
+	LastInform(id cwmp.Identity) (*cwmp.Inform, error)
+	PutInform(id cwmp.Identity, m *cwmp.Inform) error
+}
+
+type ParameterStore interface {
+	// Parameters returns the cached values of the device sorted by name.
+	Parameters(id cwmp.Identity) ([]cwmp.ParameterValue, error)
+
+	// PutParameters merges params into the cached values of the device.
+	PutParameters(id cwmp.Identity, params []cwmp.ParameterValue) error
+}
+
+type TaskStore interface {
+	// Tasks returns the tasks of the device in the order they were added.
+	Tasks(id cwmp.Identity) ([]*Task, error)
+
+	// PutTask adds t, or replaces the task of the device with the same ID
+	// keeping its place in the order.
+	PutTask(t *Task) error
+	DeleteTask(id cwmp.Identity, taskID string) error
+}
+
+// SessionStore holds the sessions in progress. A server deletes those left
+// by an earlier run when it handles its first request, as their connections
+// are gone and they can't be resumed.
+type SessionStore interface {
+	Session(id string) (*SessionState, error)
+	Sessions() ([]*SessionState, error)
+	PutSession(s *SessionState) error
+	DeleteSession(id string) error
+}
+
+// Store persists the state of an ACS. Records are copied in and out, so
+// changing a record doesn't change the store until it is put back.
+type Store interface {
+	DeviceStore
+	InformStore
+	ParameterStore
+	TaskStore
+	SessionStore
+}
+
+func mergeParameters(cached, params []cwmp.ParameterValue) []cwmp.ParameterValue {
+	byName := make(map[string]int, len(cached))
+	for i, p := range cached {
+		byName[p.Name] = i
+	}
+
+	merged := append([]cwmp.ParameterValue(nil), cached...)
+
+	for _, p := range params {
+		i, ok := byName[p.Name]
+		if ok {
+			merged[i] = p
+			continue
+		}
+
+		byName[p.Name] = len(merged)
+		merged = append(merged, p)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Name < merged[j].Name
+	})
+
+	return merged
+}
+
+func putTask(tasks []*Task, t *Task) []*Task {
+	for i, task := range tasks {
+		if task.ID == t.ID {
+			tasks[i] = t
+			return tasks
+		}
+	}
+
+	return append(tasks, t)
+}
+
+func deleteTask(tasks []*Task, id string) ([]*Task, bool) {
+	for i, task := range tasks {
+		if task.ID == id {
+			return append(tasks[:i:i], tasks[i+1:]...), true
+		}
+	}
+
+	return tasks, false
+}
+
+// store returns the Store of the server, an in-memory one if it has none.
+func (s *Server) store() Store {
+	if s.Store != nil {
+		return s.Store
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.memStore == nil {
+		s.memStore = &MemoryStore{}
+	}
+
+	return s.memStore
+}
+
+// recordInform stores the device, its Inform and the parameters it reported.
+func (s *Server) recordInform(device cwmp.Identity, m *cwmp.Inform) {
+	now := time.Now()
+
+	d, err := s.store().Device(device)
+	if err == ErrNotFound {
+		d = &Device{ID: device, FirstSeen: now}
+		err = nil
+	}
+
+	if err == nil {
+		d.Summary = m.Summary()
+		d.LastSeen = now
+		err = s.store().PutDevice(d)
+	}
+
+	if err == nil {
+		err = s.store().PutInform(device, m)
+	}
+
+	if err == nil {
+		err = s.store().PutParameters(device, m.ParameterList)
+	}
+
+	if err != nil {
+		s.logf("acs: %s: %v", device, err)
+	}
+}
+
+func (s *Server) saveSession(sess *session) {
+	s.mu.Lock()
+	lastSeen := sess.lastSeen
+	s.mu.Unlock()
+
+	err := s.store().PutSession(&SessionState{
+		ID:        sess.id,
+		Device:    sess.device,
+		Namespace: sess.namespace,
+		Started:   sess.started,
+		LastSeen:  lastSeen,
+	})
+	if err != nil {
+		s.logf("acs: %s: %v", sess.device, err)
+	}
+}
+
+// pruneSessions deletes the stored sessions that aren't in progress, such as
+// those cut off by a restart. Tasks they left sent are put back in the queue
+// by tidyTasks when the device starts its next session.
+func (s *Server) pruneSessions() {
+	stored, err := s.store().Sessions()
+	if err != nil {
+		s.logf("acs: %v", err)
+		return
+	}
+
+	for _, st := range stored {
+		s.mu.Lock()
+		_, ok := s.sessions[st.ID]
+		s.mu.Unlock()
+
+		if ok {
+			continue
+		}
+
+		err := s.store().DeleteSession(st.ID)
+		if err != nil && err != ErrNotFound {
+			s.logf("acs: %s: %v", st.Device, err)
+		}
+	}
+}
+
+func (s *Server) forgetSession(sess *session) {
+	err := s.store().DeleteSession(sess.id)
+	if err != nil && err != ErrNotFound {
+		s.logf("acs: %s: %v", sess.device, err)
+	}
+}
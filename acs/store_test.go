@@ -0,0 +1,374 @@
+package acs
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+var otherDevice = cwmp.Identity{OUI: "00D09E", SerialNumber: "2"}
+
+// testStore checks that a store behaves like any other. reopen returns the
+// same store as it would be found after a restart.
+func testStore(t *testing.T, s Store, reopen func() Store) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Devices", func(t *testing.T) {
+		_, err := s.Device(testDevice)
+		if err != ErrNotFound {
+			t.Fatalf("Expected (%v) got (%v)", ErrNotFound, err)
+		}
+
+		d := &Device{
+			ID:        testDevice,
+			Summary:   cwmp.DeviceSummary{Root: cwmp.RootDevice, SoftwareVersion: "7.1"},
+			FirstSeen: now,
+			LastSeen:  now,
+		}
+
+		for _, d := range []*Device{d, {ID: otherDevice, FirstSeen: now, LastSeen: now}} {
+			err = s.PutDevice(d)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+
+		d.LastSeen = now.Add(time.Hour)
+
+		got, err := s.Device(testDevice)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if got.LastSeen != now || got.Summary.SoftwareVersion != "7.1" {
+			t.Fatalf("Unexpected device (%v)", got)
+		}
+
+		devices, err := s.Devices()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if len(devices) != 2 || devices[0].ID != otherDevice || devices[1].ID != testDevice {
+			t.Fatalf("Unexpected devices (%v)", devices)
+		}
+	})
+
+	t.Run("Inform", func(t *testing.T) {
+		_, err := s.LastInform(otherDevice)
+		if err != ErrNotFound {
+			t.Fatalf("Expected (%v) got (%v)", ErrNotFound, err)
+		}
+
+		m := &cwmp.Inform{
+			DeviceID:    cwmp.DeviceID{OUI: "E48D8C", ProductClass: "hAP", SerialNumber: "1"},
+			CurrentTime: cwmp.DateTime{Time: now},
+			Event:       cwmp.EventList{{EventCode: cwmp.EventBoot}},
+			ParameterList: cwmp.ParameterValueList{
+				{Name: "Device.DeviceInfo.SoftwareVersion", Value: "7.1", Type: cwmp.TypeString},
+			},
+		}
+
+		err = s.PutInform(testDevice, m)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		got, err := s.LastInform(testDevice)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if got.Identity() != testDevice || !got.CurrentTime.Equal(now) || !got.HasEvent(cwmp.EventBoot) {
+			t.Fatalf("Unexpected Inform (%v)", got)
+		}
+
+		if len(got.ParameterList) != 1 || got.ParameterList[0] != m.ParameterList[0] {
+			t.Fatalf("Unexpected parameters (%v)", got.ParameterList)
+		}
+	})
+
+	t.Run("Parameters", func(t *testing.T) {
+		for _, params := range [][]cwmp.ParameterValue{
+			{
+				{Name: "Device.DeviceInfo.SoftwareVersion", Value: "7.1", Type: cwmp.TypeString},
+				{Name: "Device.DeviceInfo.UpTime", Value: "10", Type: cwmp.TypeUnsignedInt},
+			},
+			{
+				{Name: "Device.DeviceInfo.UpTime", Value: "20", Type: cwmp.TypeUnsignedInt},
+				{Name: "Device.DeviceInfo.HardwareVersion", Value: "RB951", Type: cwmp.TypeString},
+			},
+		} {
+			err := s.PutParameters(testDevice, params)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+
+		got, err := s.Parameters(testDevice)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		want := []cwmp.ParameterValue{
+			{Name: "Device.DeviceInfo.HardwareVersion", Value: "RB951", Type: cwmp.TypeString},
+			{Name: "Device.DeviceInfo.SoftwareVersion", Value: "7.1", Type: cwmp.TypeString},
+			{Name: "Device.DeviceInfo.UpTime", Value: "20", Type: cwmp.TypeUnsignedInt},
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Expected (%v) got (%v)", want, got)
+		}
+
+		got, err = s.Parameters(otherDevice)
+		if err != nil || len(got) != 0 {
+			t.Fatalf("Unexpected parameters (%v) (%v)", got, err)
+		}
+	})
+
+	t.Run("Tasks", func(t *testing.T) {
+		for _, task := range []*Task{
+			{ID: "1", Device: testDevice, Request: &cwmp.GetParameterValues{ParameterNames: cwmp.StringList{"Device."}}, Created: now},
+			{ID: "2", Device: testDevice, Request: &cwmp.Reboot{CommandKey: "reboot"}, Created: now},
+			{ID: "3", Device: testDevice, Request: &cwmp.FactoryReset{}, State: TaskFaulted, Created: now, Fault: cwmp.NewFault(cwmp.CPERequestDenied)},
+			{ID: "1", Device: testDevice, Request: &cwmp.GetParameterValues{ParameterNames: cwmp.StringList{"Device.DeviceInfo."}}, State: TaskDone, Created: now, Expires: now.Add(time.Hour), Response: &cwmp.GetParameterValuesResponse{}},
+		} {
+			err := s.PutTask(task)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+
+		err := s.DeleteTask(testDevice, "2")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		err = s.DeleteTask(testDevice, "2")
+		if err != ErrNotFound {
+			t.Fatalf("Expected (%v) got (%v)", ErrNotFound, err)
+		}
+
+		tasks, err := s.Tasks(testDevice)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if len(tasks) != 2 || tasks[0].ID != "1" || tasks[1].ID != "3" {
+			t.Fatalf("Unexpected tasks (%v)", tasks)
+		}
+
+		req, ok := tasks[0].Request.(*cwmp.GetParameterValues)
+		if !ok || len(req.ParameterNames) != 1 || req.ParameterNames[0] != "Device.DeviceInfo." {
+			t.Fatalf("Unexpected request (%v)", tasks[0].Request)
+		}
+
+		if _, ok := tasks[0].Response.(*cwmp.GetParameterValuesResponse); !ok || tasks[0].State != TaskDone || !tasks[0].Expires.Equal(now.Add(time.Hour)) {
+			t.Fatalf("Unexpected task (%v)", tasks[0])
+		}
+
+		if _, ok := tasks[1].Request.(*cwmp.FactoryReset); !ok || tasks[1].Device != testDevice || !tasks[1].Created.Equal(now) {
+			t.Fatalf("Unexpected task (%v)", tasks[1])
+		}
+
+		if tasks[1].State != TaskFaulted || tasks[1].Fault == nil || tasks[1].Fault.Code != cwmp.CPERequestDenied || tasks[1].Response != nil {
+			t.Fatalf("Unexpected task (%v)", tasks[1])
+		}
+	})
+
+	t.Run("Sessions", func(t *testing.T) {
+		_, err := s.Session("a")
+		if err != ErrNotFound {
+			t.Fatalf("Expected (%v) got (%v)", ErrNotFound, err)
+		}
+
+		for _, state := range []*SessionState{
+			{ID: "b", Device: otherDevice, Started: now, LastSeen: now},
+			{ID: "a", Device: testDevice, Namespace: "urn:dslforum-org:cwmp-1-4", Started: now, LastSeen: now},
+		} {
+			err = s.PutSession(state)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+
+		got, err := s.Session("a")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if got.Device != testDevice || got.Namespace != "urn:dslforum-org:cwmp-1-4" || !got.Started.Equal(now) {
+			t.Fatalf("Unexpected session (%v)", got)
+		}
+
+		err = s.DeleteSession("b")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		err = s.DeleteSession("b")
+		if err != ErrNotFound {
+			t.Fatalf("Expected (%v) got (%v)", ErrNotFound, err)
+		}
+
+		sessions, err := s.Sessions()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if len(sessions) != 1 || sessions[0].ID != "a" {
+			t.Fatalf("Unexpected sessions (%v)", sessions)
+		}
+	})
+
+	if reopen != nil {
+		s = reopen()
+
+		t.Run("Reopen", func(t *testing.T) {
+			devices, err := s.Devices()
+			if err != nil || len(devices) != 2 {
+				t.Fatalf("Unexpected devices (%v) (%v)", devices, err)
+			}
+
+			tasks, err := s.Tasks(testDevice)
+			if err != nil || len(tasks) != 2 {
+				t.Fatalf("Unexpected tasks (%v) (%v)", tasks, err)
+			}
+
+			params, err := s.Parameters(testDevice)
+			if err != nil || len(params) != 3 {
+				t.Fatalf("Unexpected parameters (%v) (%v)", params, err)
+			}
+
+			_, err = s.Session("a")
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		})
+	}
+
+	t.Run("DeleteDevice", func(t *testing.T) {
+		err := s.DeleteDevice(testDevice)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		err = s.DeleteDevice(testDevice)
+		if err != ErrNotFound {
+			t.Fatalf("Expected (%v) got (%v)", ErrNotFound, err)
+		}
+
+		_, err = s.LastInform(testDevice)
+		if err != ErrNotFound {
+			t.Fatalf("Expected (%v) got (%v)", ErrNotFound, err)
+		}
+
+		tasks, err := s.Tasks(testDevice)
+		if err != nil || len(tasks) != 0 {
+			t.Fatalf("Unexpected tasks (%v) (%v)", tasks, err)
+		}
+
+		params, err := s.Parameters(testDevice)
+		if err != nil || len(params) != 0 {
+			t.Fatalf("Unexpected parameters (%v) (%v)", params, err)
+		}
+
+		devices, err := s.Devices()
+		if err != nil || len(devices) != 1 {
+			t.Fatalf("Unexpected devices (%v) (%v)", devices, err)
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, &MemoryStore{}, nil)
+}
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	testStore(t, &FileStore{Dir: dir}, func() Store {
+		return &FileStore{Dir: dir}
+	})
+}
+
+func TestServerStore(t *testing.T) {
+	store := &MemoryStore{}
+
+	s := &Server{Store: store}
+
+	post(t, s, `<cwmp:Inform><DeviceId><OUI>E48D8C</OUI><ProductClass>hAP</ProductClass><SerialNumber>1</SerialNumber></DeviceId><ParameterList><ParameterValueStruct><Name>Device.DeviceInfo.SoftwareVersion</Name><Value>7.1</Value></ParameterValueStruct></ParameterList></cwmp:Inform>`)
+
+	d, err := store.Device(testDevice)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if d.Summary.SoftwareVersion != "7.1" || d.FirstSeen.IsZero() {
+		t.Fatalf("Unexpected device (%v)", d)
+	}
+
+	_, err = store.LastInform(testDevice)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	params, err := store.Parameters(testDevice)
+	if err != nil || len(params) != 1 {
+		t.Fatalf("Unexpected parameters (%v) (%v)", params, err)
+	}
+
+	sessions, err := store.Sessions()
+	if err != nil || len(sessions) != 1 || sessions[0].Device != testDevice {
+		t.Fatalf("Unexpected sessions (%v) (%v)", sessions, err)
+	}
+
+	_, _, err = s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	sessions, err = store.Sessions()
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("Unexpected sessions (%v) (%v)", sessions, err)
+	}
+}
+
+func TestServerPrunesSessions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	// A session left behind by an earlier run.
+	store := &FileStore{Dir: dir}
+
+	err = store.PutSession(&SessionState{ID: "stale", Device: otherDevice})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	s := &Server{Store: store}
+
+	post(t, s, testInform)
+
+	sessions, err := store.Sessions()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if len(sessions) != 1 || sessions[0].Device != testDevice {
+		t.Fatalf("Unexpected sessions (%v)", sessions)
+	}
+}
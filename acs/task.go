@@ -0,0 +1,301 @@
+package acs
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+// DefaultTaskRetention is how long finished tasks are kept if
+// Server.TaskRetention is zero.
+const DefaultTaskRetention = 24 * time.Hour
+
+type TaskState int
+
+const (
+	// TaskPending tasks are waiting to be sent in the next session.
+	TaskPending TaskState = iota
+
+	// TaskSent tasks have been sent and are waiting for the response.
+	TaskSent
+
+	// TaskDone tasks were answered with a response.
+	TaskDone
+
+	// TaskFaulted tasks were answered with a fault.
+	TaskFaulted
+
+	// TaskExpired tasks weren't sent before they expired.
+	TaskExpired
+)
+
+func (s TaskState) String() string {
+	switch s {
+	case TaskPending:
+		return "Pending"
+	case TaskSent:
+		return "Sent"
+	case TaskDone:
+		return "Done"
+	case TaskFaulted:
+		return "Faulted"
+	case TaskExpired:
+		return "Expired"
+	}
+
+	return fmt.Sprintf("TaskState(%d)", int(s))
+}
+
+// Task is an RPC to send to a device in one of its sessions.
+type Task struct {
+	ID      string
+	Device  cwmp.Identity
+	Request cwmp.Message
+	State   TaskState
+	Created time.Time
+
+	// Expires is when the task expires if it hasn't been sent, or zero if
+	// it never does.
+	Expires time.Time
+
+	Sent     time.Time
+	Finished time.Time
+
+	// Response is the response of a TaskDone task, Fault the fault of a
+	// TaskFaulted one.
+	Response cwmp.Message
+	Fault    *cwmp.Fault
+}
+
+// IsFinished reports whether the task is done, faulted or expired.
+func (t *Task) IsFinished() bool {
+	return t.State == TaskDone || t.State == TaskFaulted || t.State == TaskExpired
+}
+
+// Enqueue adds req to the tasks of device. It is sent after the CPE's own
+// requests in the device's next session, or the current one if a session is
+// in progress, unless it expires first. A zero expires never expires.
+func (s *Server) Enqueue(device cwmp.Identity, req cwmp.Message, expires time.Time) (*Task, error) {
+	return s.enqueue(device, req, expires, nil)
+}
+
+// enqueue adds a task, registering ch to receive it once it finishes before
+// it can be sent.
+func (s *Server) enqueue(device cwmp.Identity, req cwmp.Message, expires time.Time, ch chan *Task) (*Task, error) {
+	sender := cwmp.Sender(req)
+	if req.IsResponse() || sender != 0 && sender&cwmp.ACS == 0 {
+		return nil, fmt.Errorf("acs: (%s) can't be sent to a CPE", req.Method().Name)
+	}
+
+	t := &Task{
+		ID:      newID(),
+		Device:  device,
+		Request: req,
+		State:   TaskPending,
+		Created: time.Now(),
+		Expires: expires,
+	}
+
+	if ch != nil {
+		s.wait(t.ID, ch)
+	}
+
+	err := s.store().PutTask(t)
+	if err != nil {
+		s.unwait(t.ID)
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (s *Server) taskRetention() time.Duration {
+	if s.TaskRetention > 0 {
+		return s.TaskRetention
+	}
+
+	return DefaultTaskRetention
+}
+
+// tidyTasks is called when device starts a session. Tasks left sent by a
+// session that never finished, such as one cut short by a restart, are put
+// back in the queue, and finished tasks past their retention are deleted.
+func (s *Server) tidyTasks(device cwmp.Identity) {
+	tasks, err := s.store().Tasks(device)
+	if err != nil {
+		s.logf("acs: %s: %v", device, err)
+		return
+	}
+
+	retention := s.taskRetention()
+	now := time.Now()
+
+	for _, t := range tasks {
+		switch {
+		case t.State == TaskSent:
+			s.requeueTask(t)
+		case t.IsFinished() && now.Sub(t.Finished) > retention:
+			err = s.store().DeleteTask(device, t.ID)
+			if err != nil && err != ErrNotFound {
+				s.logf("acs: %s: %v", device, err)
+			}
+		}
+	}
+}
+
+// nextTask returns the first pending task of device, expiring those that
+// are past their expiry on the way.
+func (s *Server) nextTask(device cwmp.Identity) *Task {
+	tasks, err := s.store().Tasks(device)
+	if err != nil {
+		s.logf("acs: %s: %v", device, err)
+		return nil
+	}
+
+	now := time.Now()
+
+	for _, t := range tasks {
+		if t.State != TaskPending {
+			continue
+		}
+
+		if !t.Expires.IsZero() && now.After(t.Expires) {
+			s.expireTask(t)
+			continue
+		}
+
+		return t
+	}
+
+	return nil
+}
+
+// storedTask returns the task of device with the given ID as it is in the
+// Store.
+func (s *Server) storedTask(device cwmp.Identity, id string) (*Task, error) {
+	tasks, err := s.store().Tasks(device)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range tasks {
+		if t.ID == id {
+			return t, nil
+		}
+	}
+
+	return nil, ErrNotFound
+}
+
+func (s *Server) putTask(t *Task) {
+	err := s.store().PutTask(t)
+	if err != nil {
+		s.logf("acs: %s: %v", t.Device, err)
+	}
+
+	if t.IsFinished() {
+		s.taskFinished(t)
+	}
+}
+
+func (s *Server) taskFinished(t *Task) {
+	s.notify(t)
+
+	if s.OnTaskFinished != nil {
+		s.OnTaskFinished(t)
+	}
+}
+
+// sendTask marks t sent. It reports false if t is no longer pending in the
+// Store, because it was cancelled or expired since it was fetched.
+func (s *Server) sendTask(t *Task) bool {
+	s.taskMu.Lock()
+	defer s.taskMu.Unlock()
+
+	stored, err := s.storedTask(t.Device, t.ID)
+	if err != nil || stored.State != TaskPending {
+		return false
+	}
+
+	t.State = TaskSent
+	t.Sent = time.Now()
+
+	err = s.store().PutTask(t)
+	if err != nil {
+		s.logf("acs: %s: %v", t.Device, err)
+	}
+
+	return true
+}
+
+// expireTask expires t if it is still pending in the Store.
+func (s *Server) expireTask(t *Task) {
+	s.taskMu.Lock()
+
+	stored, err := s.storedTask(t.Device, t.ID)
+
+	ok := err == nil && stored.State == TaskPending
+	if ok {
+		stored.State = TaskExpired
+		stored.Finished = time.Now()
+
+		err = s.store().PutTask(stored)
+		if err != nil {
+			s.logf("acs: %s: %v", t.Device, err)
+		}
+	}
+
+	s.taskMu.Unlock()
+
+	if ok {
+		s.taskFinished(stored)
+	}
+}
+
+// requeueTask puts a task that was sent but never answered back in the queue
+// for the next session.
+func (s *Server) requeueTask(t *Task) {
+	t.State = TaskPending
+	t.Sent = time.Time{}
+	s.putTask(t)
+}
+
+// completeTask records the response to a task, or the fault in err. The
+// values a device reported or accepted are cached in the Store.
+func (s *Server) completeTask(t *Task, resp cwmp.Message, err error) {
+	t.Finished = time.Now()
+
+	if err != nil {
+		t.State = TaskFaulted
+
+		if !errors.As(err, &t.Fault) {
+			t.Fault = cwmp.NewFault(cwmp.CPEInternalError)
+			t.Fault.String = err.Error()
+		}
+	} else {
+		t.State = TaskDone
+		t.Response = resp
+	}
+
+	var params []cwmp.ParameterValue
+
+	switch r := resp.(type) {
+	case *cwmp.GetParameterValuesResponse:
+		params = r.ParameterList
+	case *cwmp.SetParameterValuesResponse:
+		if req, ok := t.Request.(*cwmp.SetParameterValues); ok {
+			params = req.ParameterList
+		}
+	}
+
+	if params != nil {
+		err = s.store().PutParameters(t.Device, params)
+		if err != nil {
+			s.logf("acs: %s: %v", t.Device, err)
+		}
+	}
+
+	s.putTask(t)
+}
@@ -0,0 +1,269 @@
+package acs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+func assertTask(t *testing.T, s *Server, id string, state TaskState) *Task {
+	tasks, err := s.store().Tasks(testDevice)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, task := range tasks {
+		if task.ID != id {
+			continue
+		}
+
+		if task.State != state {
+			t.Fatalf("Expected (%s) got (%s)", state, task.State)
+		}
+
+		return task
+	}
+
+	t.Fatalf("Task (%s) not found", id)
+
+	return nil
+}
+
+func TestEnqueueInvalid(t *testing.T) {
+	s := &Server{}
+
+	for _, req := range []cwmp.Message{&cwmp.Inform{}, &cwmp.RebootResponse{}} {
+		_, err := s.Enqueue(testDevice, req, time.Time{})
+		if err == nil {
+			t.Fatalf("Expected error for (%T)", req)
+		}
+	}
+}
+
+func TestTaskQueue(t *testing.T) {
+	var finished []string
+
+	s := &Server{
+		OnTaskFinished: func(t *Task) {
+			finished = append(finished, t.ID)
+		},
+	}
+
+	get, err := s.Enqueue(testDevice, &cwmp.GetParameterValues{ParameterNames: cwmp.StringList{"Device.DeviceInfo.SoftwareVersion"}}, time.Time{})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	reboot, err := s.Enqueue(testDevice, &cwmp.Reboot{CommandKey: "reboot"}, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	post(t, s, testInform)
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertRequest(t, msg, "1", get.Request)
+	assertTask(t, s, get.ID, TaskSent)
+	assertTask(t, s, reboot.ID, TaskPending)
+
+	_, msg, err = s.handleMessage(newRequest("1", `<cwmp:GetParameterValuesResponse><ParameterList><ParameterValueStruct><Name>Device.DeviceInfo.SoftwareVersion</Name><Value>7.1</Value></ParameterValueStruct></ParameterList></cwmp:GetParameterValuesResponse>`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertRequest(t, msg, "2", reboot.Request)
+
+	task := assertTask(t, s, get.ID, TaskDone)
+	if _, ok := task.Response.(*cwmp.GetParameterValuesResponse); !ok {
+		t.Fatalf("Expected GetParameterValuesResponse got (%T)", task.Response)
+	}
+
+	params, err := s.store().Parameters(testDevice)
+	if err != nil || len(params) != 1 || params[0].Value != "7.1" {
+		t.Fatalf("Unexpected parameters (%v) (%v)", params, err)
+	}
+
+	_, msg, err = s.handleMessage(newRequest("2", testFault))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if msg != nil {
+		t.Fatalf("Expected session to end got (%T)", msg.Body)
+	}
+
+	task = assertTask(t, s, reboot.ID, TaskFaulted)
+	if task.Fault == nil || task.Fault.Code != cwmp.CPERequestDenied {
+		t.Fatalf("Unexpected fault (%v)", task.Fault)
+	}
+
+	if len(finished) != 2 || finished[0] != get.ID || finished[1] != reboot.ID {
+		t.Fatalf("Unexpected finished tasks (%v)", finished)
+	}
+}
+
+func TestTaskExpired(t *testing.T) {
+	var finished []*Task
+
+	s := &Server{
+		OnTaskFinished: func(t *Task) {
+			finished = append(finished, t)
+		},
+	}
+
+	task, err := s.Enqueue(testDevice, &cwmp.Reboot{}, time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	post(t, s, testInform)
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if msg != nil {
+		t.Fatalf("Expected session to end got (%T)", msg.Body)
+	}
+
+	assertTask(t, s, task.ID, TaskExpired)
+
+	if len(finished) != 1 || finished[0].ID != task.ID {
+		t.Fatalf("Unexpected finished tasks (%v)", finished)
+	}
+}
+
+func TestTaskRequeued(t *testing.T) {
+	s := &Server{}
+
+	task, err := s.Enqueue(testDevice, &cwmp.Reboot{}, time.Time{})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	post(t, s, testInform)
+
+	_, _, err = s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	s.expireSessions(time.Now().Add(DefaultSessionTimeout + time.Second))
+
+	assertTask(t, s, task.ID, TaskPending)
+
+	post(t, s, testInform)
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if _, ok := msg.Body.(*cwmp.Reboot); !ok {
+		t.Fatalf("Expected Reboot got (%T)", msg.Body)
+	}
+}
+
+func TestTaskBeforeNextRequest(t *testing.T) {
+	get := &cwmp.GetParameterValues{}
+
+	s, _ := queue(get)
+
+	post(t, s, testInform)
+
+	task, err := s.Enqueue(testDevice, &cwmp.Reboot{}, time.Time{})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertRequest(t, msg, "1", task.Request)
+
+	_, msg, err = s.handleMessage(newRequest("1", `<cwmp:RebootResponse></cwmp:RebootResponse>`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertRequest(t, msg, "2", get)
+}
+
+func TestTaskRecoveredAfterRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	s := &Server{Store: &FileStore{Dir: dir}}
+
+	task, err := s.Enqueue(testDevice, &cwmp.Reboot{CommandKey: "reboot"}, time.Time{})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	post(t, s, testInform)
+
+	_, _, err = s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertTask(t, s, task.ID, TaskSent)
+
+	// A new server on the same store, as after a restart.
+	s = &Server{Store: &FileStore{Dir: dir}}
+
+	post(t, s, testInform)
+
+	assertTask(t, s, task.ID, TaskPending)
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if r, ok := msg.Body.(*cwmp.Reboot); !ok || r.CommandKey != "reboot" {
+		t.Fatalf("Expected Reboot got (%v)", msg.Body)
+	}
+}
+
+func TestTaskRetention(t *testing.T) {
+	s := &Server{TaskRetention: time.Hour}
+
+	now := time.Now()
+
+	for _, task := range []*Task{
+		&Task{ID: "old", Device: testDevice, Request: &cwmp.Reboot{}, State: TaskDone, Finished: now.Add(-2 * time.Hour)},
+		&Task{ID: "new", Device: testDevice, Request: &cwmp.Reboot{}, State: TaskFaulted, Finished: now.Add(-time.Minute)},
+		&Task{ID: "pending", Device: testDevice, Request: &cwmp.Reboot{}, State: TaskPending, Created: now.Add(-2 * time.Hour)},
+	} {
+		err := s.store().PutTask(task)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	post(t, s, testInform)
+
+	tasks, err := s.store().Tasks(testDevice)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if len(tasks) != 2 || tasks[0].ID != "new" || tasks[1].ID != "pending" {
+		t.Fatalf("Unexpected tasks (%v)", tasks)
+	}
+}
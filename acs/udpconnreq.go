@@ -0,0 +1,233 @@
+package acs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+	"github.com/scottlangendyk/go-cwmp/stun"
+)
+
+// UDPConnectionRequest is a connection request sent over UDP as in TR-069
+// Annex G, to reach CPEs behind a NAT.
+type UDPConnectionRequest struct {
+	Timestamp time.Time
+	ID        string
+	Username  string
+	CNonce    string
+	Signature string
+}
+
+// NewUDPConnectionRequest returns a request from username signed with
+// password, with a new ID and cnonce.
+func NewUDPConnectionRequest(username, password string) (*UDPConnectionRequest, error) {
+	b := make([]byte, 12)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &UDPConnectionRequest{
+		Timestamp: time.Now(),
+		ID:        strconv.FormatUint(uint64(binary.BigEndian.Uint32(b[:4])), 10),
+		Username:  username,
+		CNonce:    strings.ToUpper(hex.EncodeToString(b[4:])),
+	}
+
+	r.Signature = r.sign(password)
+
+	return r, nil
+}
+
+// sign returns the HMAC-SHA1 of ts, id, un and cn keyed with password.
+func (r *UDPConnectionRequest) sign(password string) string {
+	h := hmac.New(sha1.New, []byte(password))
+	fmt.Fprintf(h, "%d%s%s%s", r.Timestamp.Unix(), r.ID, r.Username, r.CNonce)
+
+	return strings.ToUpper(hex.EncodeToString(h.Sum(nil)))
+}
+
+// Verify reports whether the request was signed with password.
+func (r *UDPConnectionRequest) Verify(password string) bool {
+	return hmac.Equal([]byte(strings.ToUpper(r.Signature)), []byte(r.sign(password)))
+}
+
+// Marshal encodes the request as the HTTP GET sent to addr.
+func (r *UDPConnectionRequest) Marshal(addr string) []byte {
+	query := []string{
+		"ts=" + strconv.FormatInt(r.Timestamp.Unix(), 10),
+		"id=" + url.QueryEscape(r.ID),
+		"un=" + url.QueryEscape(r.Username),
+		"cn=" + url.QueryEscape(r.CNonce),
+		"sig=" + r.Signature,
+	}
+
+	return []byte("GET http://" + addr + "?" + strings.Join(query, "&") + " HTTP/1.1\r\nHost: " + addr + "\r\n\r\n")
+}
+
+// ParseUDPConnectionRequest decodes a UDP connection request, as a CPE would.
+func ParseUDPConnectionRequest(b []byte) (*UDPConnectionRequest, error) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Method != http.MethodGet {
+		return nil, fmt.Errorf("acs: Invalid UDP connection request method (%s)", req.Method)
+	}
+
+	q := req.URL.Query()
+
+	for _, k := range []string{"ts", "id", "un", "cn", "sig"} {
+		if q.Get(k) == "" {
+			return nil, fmt.Errorf("acs: UDP connection request is missing (%s)", k)
+		}
+	}
+
+	ts, err := strconv.ParseInt(q.Get("ts"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("acs: Invalid UDP connection request timestamp (%s)", q.Get("ts"))
+	}
+
+	return &UDPConnectionRequest{
+		Timestamp: time.Unix(ts, 0),
+		ID:        q.Get("id"),
+		Username:  q.Get("un"),
+		CNonce:    q.Get("cn"),
+		Signature: q.Get("sig"),
+	}, nil
+}
+
+// Default retransmissions of a UDPConnectionRequester.
+const (
+	DefaultUDPConnectionRequestCount    = 3
+	DefaultUDPConnectionRequestInterval = time.Second
+)
+
+// UDPConnectionRequester sends UDP connection requests to the
+// UDPConnectionRequestAddress that devices report in their Informs. There is
+// no answer to a UDP connection request, so it is sent Count times, Interval
+// apart, and only failures to send are reported.
+type UDPConnectionRequester struct {
+	// Store and Credentials are as for an HTTPConnectionRequester.
+	Store       Store
+	Credentials func(device cwmp.Identity) (username, password string, err error)
+
+	// Address, if set, returns the address to send to instead of the one
+	// from the Inform, such as one learned by a stun.Server.
+	Address func(device cwmp.Identity) (string, error)
+
+	Count    int
+	Interval time.Duration
+}
+
+// STUNAddress returns an Address for a UDPConnectionRequester that sends to
+// the binding srv learned for a device. A device's binding is found by the
+// value of its ManagementServer.STUNUsername parameter cached in store. The
+// binding is only as trustworthy as the STUN password checked by srv, which
+// learns nothing without a Password, so give each device its own.
+func STUNAddress(store Store, srv *stun.Server) func(device cwmp.Identity) (string, error) {
+	return func(device cwmp.Identity) (string, error) {
+		params, err := store.Parameters(device)
+		if err != nil {
+			return "", err
+		}
+
+		for _, p := range params {
+			if !strings.HasSuffix(p.Name, ".ManagementServer.STUNUsername") || p.Value == "" {
+				continue
+			}
+
+			b, ok := srv.Binding(p.Value)
+			if ok {
+				return b.Addr.String(), nil
+			}
+		}
+
+		return "", ErrNoURL
+	}
+}
+
+func (c *UDPConnectionRequester) ConnectionRequest(ctx context.Context, device cwmp.Identity) error {
+	var addr string
+	var err error
+
+	if c.Address != nil {
+		addr, err = c.Address(device)
+	} else {
+		addr, err = informAddress(c.Store, device, func(s *cwmp.DeviceSummary) string {
+			return s.UDPConnectionRequestAddress
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	username, password, err := deviceCredentials(c.Credentials, device)
+	if err != nil {
+		return err
+	}
+
+	return c.Send(ctx, addr, username, password)
+}
+
+// Send sends a connection request to addr, a host:port.
+func (c *UDPConnectionRequester) Send(ctx context.Context, addr, username, password string) error {
+	count := c.Count
+	if count == 0 {
+		count = DefaultUDPConnectionRequestCount
+	}
+
+	interval := c.Interval
+	if interval == 0 {
+		interval = DefaultUDPConnectionRequestInterval
+	}
+
+	req, err := NewUDPConnectionRequest(username, password)
+	if err != nil {
+		return err
+	}
+
+	var d net.Dialer
+
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+
+	defer conn.Close()
+
+	b := req.Marshal(addr)
+
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		_, err = conn.Write(b)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUnreachable, err)
+		}
+	}
+
+	return nil
+}
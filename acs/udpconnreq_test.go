@@ -0,0 +1,238 @@
+package acs
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+	"github.com/scottlangendyk/go-cwmp/stun"
+)
+
+func TestUDPConnectionRequestSignature(t *testing.T) {
+	r := &UDPConnectionRequest{
+		Timestamp: time.Unix(1120673700, 0),
+		ID:        "1234",
+		Username:  "Q5xo",
+		CNonce:    "XTGRWIPC6TBAN",
+	}
+
+	r.Signature = r.sign("xyz")
+
+	if len(r.Signature) != 40 || strings.ToUpper(r.Signature) != r.Signature {
+		t.Fatalf("Unexpected signature (%s)", r.Signature)
+	}
+
+	if !r.Verify("xyz") || r.Verify("abc") {
+		t.Fatalf("Unexpected verification of (%s)", r.Signature)
+	}
+
+	b := r.Marshal("192.0.2.1:7547")
+
+	want := "GET http://192.0.2.1:7547?ts=1120673700&id=1234&un=Q5xo&cn=XTGRWIPC6TBAN&sig=" + r.Signature + " HTTP/1.1\r\nHost: 192.0.2.1:7547\r\n\r\n"
+	if string(b) != want {
+		t.Fatalf("Expected (%q) got (%q)", want, b)
+	}
+
+	got, err := ParseUDPConnectionRequest(b)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if *got != *r || !got.Verify("xyz") {
+		t.Fatalf("Expected (%v) got (%v)", r, got)
+	}
+}
+
+func TestParseUDPConnectionRequestInvalid(t *testing.T) {
+	for _, b := range []string{
+		"GET http://192.0.2.1:7547?ts=1&id=1&un=a&cn=b HTTP/1.1\r\nHost: 192.0.2.1\r\n\r\n",
+		"GET http://192.0.2.1:7547?ts=x&id=1&un=a&cn=b&sig=c HTTP/1.1\r\nHost: 192.0.2.1\r\n\r\n",
+		"POST http://192.0.2.1:7547?ts=1&id=1&un=a&cn=b&sig=c HTTP/1.1\r\nHost: 192.0.2.1\r\n\r\n",
+		"garbage",
+	} {
+		_, err := ParseUDPConnectionRequest([]byte(b))
+		if err == nil {
+			t.Fatalf("Expected error for (%q)", b)
+		}
+	}
+}
+
+func TestUDPConnectionRequester(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	defer conn.Close()
+
+	store := &MemoryStore{}
+
+	c := &UDPConnectionRequester{
+		Store: store,
+		Credentials: func(device cwmp.Identity) (string, string, error) {
+			return "admin", "secret", nil
+		},
+		Count:    2,
+		Interval: time.Millisecond,
+	}
+
+	err = c.ConnectionRequest(context.Background(), testDevice)
+	if err != ErrNoURL {
+		t.Fatalf("Expected (%v) got (%v)", ErrNoURL, err)
+	}
+
+	s := &Server{Store: store}
+
+	post(t, s, `<cwmp:Inform><DeviceId><OUI>E48D8C</OUI><ProductClass>hAP</ProductClass><SerialNumber>1</SerialNumber></DeviceId><ParameterList><ParameterValueStruct><Name>Device.ManagementServer.UDPConnectionRequestAddress</Name><Value>`+conn.LocalAddr().String()+`</Value></ParameterValueStruct></ParameterList></cwmp:Inform>`)
+
+	err = c.ConnectionRequest(context.Background(), testDevice)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var ids []string
+
+	for i := 0; i < 2; i++ {
+		buf := make([]byte, 1500)
+
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		r, err := ParseUDPConnectionRequest(buf[:n])
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if r.Username != "admin" || !r.Verify("secret") {
+			t.Fatalf("Unexpected request (%v)", r)
+		}
+
+		ids = append(ids, r.ID)
+	}
+
+	if ids[0] != ids[1] {
+		t.Fatalf("Expected retransmissions with the same ID got (%v)", ids)
+	}
+}
+
+func TestUDPConnectionRequesterSTUN(t *testing.T) {
+	stunConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	learned := make(chan stun.Binding, 1)
+
+	srv := &stun.Server{
+		Password: func(username string) (string, bool) {
+			return "stun-secret", username == "stun-cpe"
+		},
+		OnBinding: func(b stun.Binding) {
+			learned <- b
+		},
+	}
+	defer srv.Close()
+
+	go srv.Serve(stunConn)
+
+	store := &MemoryStore{}
+
+	s := &Server{Store: store}
+
+	// The ACS sets the STUN username of the device.
+	task, err := s.Enqueue(testDevice, &cwmp.SetParameterValues{
+		ParameterList: cwmp.ParameterValueList{
+			cwmp.ParameterValue{Name: "Device.ManagementServer.STUNUsername", Value: "stun-cpe"},
+		},
+	}, time.Time{})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	post(t, s, testInform)
+
+	_, msg, err := s.handleMessage(newRequest("", ""))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertRequest(t, msg, "1", task.Request)
+
+	_, _, err = s.handleMessage(newRequest("1", `<cwmp:SetParameterValuesResponse><Status>0</Status></cwmp:SetParameterValuesResponse>`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	c := &UDPConnectionRequester{
+		Store:   store,
+		Address: STUNAddress(store, srv),
+		Credentials: func(device cwmp.Identity) (string, string, error) {
+			return "admin", "secret", nil
+		},
+		Count: 1,
+	}
+
+	err = c.ConnectionRequest(context.Background(), testDevice)
+	if err != ErrNoURL {
+		t.Fatalf("Expected (%v) got (%v)", ErrNoURL, err)
+	}
+
+	// The CPE reports its binding with the username it was given.
+	cpe, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	defer cpe.Close()
+
+	req := &stun.Message{Type: stun.BindingRequest}
+	req.Add(stun.AttrUsername, []byte("stun-cpe"))
+	req.Add(stun.AttrConnectionRequestBinding, []byte(stun.ConnectionRequestBinding))
+	req.AddMessageIntegrity("stun-secret")
+
+	_, err = cpe.WriteTo(req.Marshal(), stunConn.LocalAddr())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	<-learned
+
+	err = c.ConnectionRequest(context.Background(), testDevice)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	cpe.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 1500)
+
+	for {
+		n, _, err := cpe.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// Skip the STUN Binding Response.
+		if _, err := stun.Parse(buf[:n]); err == nil {
+			continue
+		}
+
+		r, err := ParseUDPConnectionRequest(buf[:n])
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if r.Username != "admin" || !r.Verify("secret") {
+			t.Fatalf("Unexpected connection request (%v)", r)
+		}
+
+		break
+	}
+}
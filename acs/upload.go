@@ -0,0 +1,236 @@
+package acs
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultMaxUploadSize is the largest file an UploadStore accepts if MaxSize
+// is zero.
+const DefaultMaxUploadSize = 32 << 20
+
+var (
+	errInvalidUploadPath = errors.New("acs: invalid upload path")
+	errUploadExists      = errors.New("acs: upload already exists")
+	errUploadTooLarge    = errors.New("acs: upload too large")
+)
+
+// UploadStore receives files sent by a CPE in response to an Upload request
+// and stores them on disk by device and CommandKey. Requests are expected at
+// <device>/<command key>, relative to wherever the store is mounted. A file
+// is only stored once, later uploads to the same path are refused.
+type UploadStore struct {
+	Dir string
+
+	// Authenticate checks the Basic credentials sent with an upload, the
+	// Username and Password of the Upload request it answers. If nil, all
+	// uploads are refused.
+	Authenticate func(device, commandKey, username, password string) bool
+
+	// MaxSize is the largest request body accepted, in bytes. Larger uploads
+	// are refused with 413 Request Entity Too Large. It defaults to
+	// DefaultMaxUploadSize.
+	MaxSize int64
+}
+
+// UploadURL returns the URL to give a CPE in an Upload request, for a store
+// mounted at base.
+func UploadURL(base, device, commandKey string) string {
+	return strings.TrimRight(base, "/") + "/" + url.PathEscape(device) + "/" + url.PathEscape(commandKey)
+}
+
+// fileName maps an arbitrary path segment to a name that is safe to use in
+// the upload directory.
+func fileName(s string) string {
+	return "_" + url.PathEscape(s)
+}
+
+func (s *UploadStore) maxSize() int64 {
+	if s.MaxSize > 0 {
+		return s.MaxSize
+	}
+
+	return DefaultMaxUploadSize
+}
+
+// limitedBody records whether http.MaxBytesReader cut the body off, which
+// can't be told from its error once multipart has wrapped it.
+type limitedBody struct {
+	io.ReadCloser
+	n, max   int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+
+	if err != nil && err != io.EOF && b.n >= b.max {
+		b.exceeded = true
+		err = errUploadTooLarge
+	}
+
+	return n, err
+}
+
+func (s *UploadStore) path(device, commandKey string) string {
+	return filepath.Join(s.Dir, fileName(device), fileName(commandKey))
+}
+
+func (s *UploadStore) Open(device, commandKey string) (*os.File, error) {
+	return os.Open(s.path(device, commandKey))
+}
+
+func (s *UploadStore) store(device, commandKey string, r io.Reader) error {
+	p := s.path(device, commandKey)
+
+	err := os.MkdirAll(filepath.Dir(p), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(p), ".upload")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	// Link rather than rename so that an existing upload is never replaced.
+	err = os.Link(f.Name(), p)
+	if os.IsExist(err) {
+		return errUploadExists
+	}
+
+	return err
+}
+
+func parseUploadPath(r *http.Request) (string, string, error) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		return "", "", errInvalidUploadPath
+	}
+
+	device, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return "", "", errInvalidUploadPath
+	}
+
+	commandKey, err := url.PathUnescape(parts[1])
+	if err != nil {
+		return "", "", errInvalidUploadPath
+	}
+
+	return device, commandKey, nil
+}
+
+// uploadBody returns the file contents of the request. Most CPEs send the raw
+// file, but some wrap a POST in multipart/form-data.
+func uploadBody(r *http.Request) (io.Reader, error) {
+	if r.Method != http.MethodPost {
+		return r.Body, nil
+	}
+
+	t, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || t != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	mr := multipart.NewReader(r.Body, params["boundary"])
+
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.FileName() != "" {
+			return p, nil
+		}
+	}
+}
+
+func (s *UploadStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "PUT, POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	device, commandKey, err := parseUploadPath(r)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok || s.Authenticate == nil || !s.Authenticate(device, commandKey, username, password) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="upload"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	max := s.maxSize()
+	if r.ContentLength > max {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	lb := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, max), max: max}
+	r.Body = lb
+
+	body, err := uploadBody(r)
+	if lb.exceeded {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = s.store(device, commandKey, body)
+	if err == errUploadExists {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	if lb.exceeded {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,206 @@
+package acs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func newUploadServer(t *testing.T) (*UploadStore, *httptest.Server, func()) {
+	dir, err := ioutil.TempDir("", "upload")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	s := &UploadStore{
+		Dir: dir,
+		Authenticate: func(device, commandKey, username, password string) bool {
+			return username == "cpe" && password == "secret"
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/upload/", http.StripPrefix("/upload", s))
+
+	srv := httptest.NewServer(mux)
+
+	return s, srv, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func assertUpload(t *testing.T, s *UploadStore, device, commandKey, want string) {
+	f, err := s.Open(device, commandKey)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	got, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if string(got) != want {
+		t.Fatalf("Doesn't match\nwant: %s\ngot:  %s", want, got)
+	}
+}
+
+func doUpload(t *testing.T, method, u, contentType string, body []byte) int {
+	return doUploadAs(t, "cpe", "secret", method, u, contentType, body)
+}
+
+func doUploadAs(t *testing.T, username, password, method, u, contentType string, body []byte) int {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	res.Body.Close()
+
+	return res.StatusCode
+}
+
+func TestUploadPut(t *testing.T) {
+	s, srv, done := newUploadServer(t)
+	defer done()
+
+	u := UploadURL(srv.URL+"/upload/", "E48D8C-hAP mini-B7B20A1DE3F0", "log/1")
+
+	got := doUpload(t, http.MethodPut, u, "", []byte("first"))
+	if got != http.StatusCreated {
+		t.Fatalf("Expected (%d) got (%d)", http.StatusCreated, got)
+	}
+
+	got = doUpload(t, http.MethodPut, u, "", []byte("second"))
+	if got != http.StatusConflict {
+		t.Fatalf("Expected (%d) got (%d)", http.StatusConflict, got)
+	}
+
+	assertUpload(t, s, "E48D8C-hAP mini-B7B20A1DE3F0", "log/1", "first")
+}
+
+func TestUploadPost(t *testing.T) {
+	s, srv, done := newUploadServer(t)
+	defer done()
+
+	got := doUpload(t, http.MethodPost, UploadURL(srv.URL+"/upload", "device", "cfg"), "application/octet-stream", []byte("config"))
+	if got != http.StatusNoContent {
+		t.Fatalf("Expected (%d) got (%d)", http.StatusNoContent, got)
+	}
+
+	assertUpload(t, s, "device", "cfg", "config")
+}
+
+func TestUploadPostMultipart(t *testing.T) {
+	s, srv, done := newUploadServer(t)
+	defer done()
+
+	var b bytes.Buffer
+
+	mw := multipart.NewWriter(&b)
+	mw.WriteField("name", "value")
+
+	fw, err := mw.CreateFormFile("file", "config.xml")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	fw.Write([]byte("<config/>"))
+	mw.Close()
+
+	got := doUpload(t, http.MethodPost, UploadURL(srv.URL+"/upload", "device", ""), mw.FormDataContentType(), b.Bytes())
+	if got != http.StatusNoContent {
+		t.Fatalf("Expected (%d) got (%d)", http.StatusNoContent, got)
+	}
+
+	assertUpload(t, s, "device", "", "<config/>")
+}
+
+func TestUploadInvalid(t *testing.T) {
+	_, srv, done := newUploadServer(t)
+	defer done()
+
+	got := doUpload(t, http.MethodGet, UploadURL(srv.URL+"/upload", "device", "key"), "", nil)
+	if got != http.StatusMethodNotAllowed {
+		t.Fatalf("Expected (%d) got (%d)", http.StatusMethodNotAllowed, got)
+	}
+
+	for _, p := range []string{"/upload/device", "/upload/a/b/c"} {
+		got = doUpload(t, http.MethodPut, srv.URL+p, "", []byte("x"))
+		if got != http.StatusNotFound {
+			t.Fatalf("%s: Expected (%d) got (%d)", p, http.StatusNotFound, got)
+		}
+	}
+
+	for _, username := range []string{"", "other"} {
+		got = doUploadAs(t, username, "secret", http.MethodPut, UploadURL(srv.URL+"/upload", "device", "key"), "", []byte("x"))
+		if got != http.StatusUnauthorized {
+			t.Fatalf("Expected (%d) got (%d)", http.StatusUnauthorized, got)
+		}
+	}
+
+	if strings.Contains(fileName(".."), "/") || fileName("..") == ".." {
+		t.Fatal("Unsafe file name")
+	}
+}
+
+func TestUploadTooLarge(t *testing.T) {
+	s, srv, done := newUploadServer(t)
+	defer done()
+
+	s.MaxSize = 4
+
+	u := UploadURL(srv.URL+"/upload", "device", "log")
+
+	got := doUpload(t, http.MethodPut, u, "", []byte("too large"))
+	if got != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected (%d) got (%d)", http.StatusRequestEntityTooLarge, got)
+	}
+
+	// Without a Content-Length the limit is only hit while reading.
+	req, err := http.NewRequest(http.MethodPut, u, ioutil.NopCloser(strings.NewReader("too large")))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req.SetBasicAuth("cpe", "secret")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Expected (%d) got (%d)", http.StatusRequestEntityTooLarge, res.StatusCode)
+	}
+
+	_, err = s.Open("device", "log")
+	if !os.IsNotExist(err) {
+		t.Fatalf("Expected (%v) got (%v)", os.ErrNotExist, err)
+	}
+
+	got = doUpload(t, http.MethodPut, u, "", []byte("fits"))
+	if got != http.StatusCreated {
+		t.Fatalf("Expected (%d) got (%d)", http.StatusCreated, got)
+	}
+}
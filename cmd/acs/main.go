@@ -0,0 +1,54 @@
+package main
+
+import (
+	"crypto/subtle"
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/acs"
+	"github.com/scottlangendyk/go-cwmp/cwmp"
+)
+
+func main() {
+	addr := flag.String("addr", "0.0.0.0:8081", "address to listen on")
+	certFile := flag.String("cert", "", "TLS certificate file")
+	keyFile := flag.String("key", "", "TLS key file")
+	uploadDir := flag.String("uploads", "uploads", "directory to store uploaded files in")
+	uploadUser := flag.String("upload-user", "", "username CPEs upload files with")
+	uploadPass := flag.String("upload-pass", "", "password CPEs upload files with")
+
+	flag.Parse()
+
+	s := &acs.Server{
+		Addr:         *addr,
+		CertFile:     *certFile,
+		KeyFile:      *keyFile,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  2 * time.Minute,
+		OnInform: func(device cwmp.Identity, m *cwmp.Inform) error {
+			log.Printf("%s: Inform %v", device, m.Event)
+			return nil
+		},
+		OnFollowUp: func(device cwmp.Identity, m *cwmp.Inform, req cwmp.Message) {
+			log.Printf("%s: Inform follows %s %v", device, req.Method().Name, req)
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", s)
+	mux.Handle("/upload/", http.StripPrefix("/upload", &acs.UploadStore{
+		Dir: *uploadDir,
+		Authenticate: func(device, commandKey, username, password string) bool {
+			return *uploadUser != "" &&
+				subtle.ConstantTimeCompare([]byte(username), []byte(*uploadUser)) == 1 &&
+				subtle.ConstantTimeCompare([]byte(password), []byte(*uploadPass)) == 1
+		},
+	}))
+
+	s.Handler = mux
+
+	log.Fatal(s.ListenAndServe())
+}
@@ -0,0 +1,191 @@
+package cwmp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+
+	"github.com/scottlangendyk/go-cwmp/soap"
+)
+
+func arrayTypeAttr(arrayType string, n int) xml.Attr {
+	return xml.Attr{
+		Name: xml.Name{
+			Space: soap.XMLSpaceEncoding,
+			Local: "arrayType",
+		},
+		Value: fmt.Sprintf("%s[%d]", arrayType, n),
+	}
+}
+
+// encodeArray encodes the slice items as a SOAP encoded array, each element
+// named elem and the whole annotated with SOAP-ENC:arrayType. The cwmp and
+// xsd prefixes used in the arrayType value are expected to be declared by the
+// prefixer.
+func encodeArray(e *xml.Encoder, start xml.StartElement, arrayType, elem string, items interface{}) error {
+	v := reflect.ValueOf(items)
+
+	start.Attr = append(start.Attr, arrayTypeAttr(arrayType, v.Len()))
+
+	err := e.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		err = e.EncodeElement(v.Index(i).Interface(), xml.StartElement{Name: xml.Name{Local: elem}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// decodeArray decodes every child element of start into a new element of the
+// slice pointed to by items, regardless of whether the sender included an
+// arrayType attribute.
+func decodeArray(d *xml.Decoder, start xml.StartElement, items interface{}) error {
+	v := reflect.ValueOf(items).Elem()
+
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch el := t.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			item := reflect.New(v.Type().Elem())
+
+			err = d.DecodeElement(item.Interface(), &el)
+			if err != nil {
+				return err
+			}
+
+			v.Set(reflect.Append(v, item.Elem()))
+		}
+	}
+}
+
+type StringList []string
+
+func (l StringList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "xsd:string", "string", l)
+}
+
+func (l *StringList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
+
+type ParameterValueList []ParameterValue
+
+func (l ParameterValueList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "cwmp:ParameterValueStruct", "ParameterValueStruct", l)
+}
+
+func (l *ParameterValueList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
+
+type ParameterInfoList []ParameterInfo
+
+func (l ParameterInfoList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "cwmp:ParameterInfoStruct", "ParameterInfoStruct", l)
+}
+
+func (l *ParameterInfoList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
+
+type ParameterAttributeList []ParameterAttribute
+
+func (l ParameterAttributeList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "cwmp:ParameterAttributeStruct", "ParameterAttributeStruct", l)
+}
+
+func (l *ParameterAttributeList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
+
+type SetParameterAttributeList []SetParameterAttribute
+
+func (l SetParameterAttributeList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "cwmp:SetParameterAttributesStruct", "SetParameterAttributesStruct", l)
+}
+
+func (l *SetParameterAttributeList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
+
+type EventList []Event
+
+func (l EventList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "cwmp:EventStruct", "EventStruct", l)
+}
+
+func (l *EventList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
+
+type TimeWindowList []TimeWindow
+
+func (l TimeWindowList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "cwmp:TimeWindowStruct", "TimeWindowStruct", l)
+}
+
+func (l *TimeWindowList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
+
+type QueuedTransferList []QueuedTransfer
+
+func (l QueuedTransferList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "cwmp:QueuedTransferStruct", "QueuedTransferStruct", l)
+}
+
+func (l *QueuedTransferList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
+
+type AllQueuedTransferList []AllQueuedTransfer
+
+func (l AllQueuedTransferList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "cwmp:AllQueuedTransferStruct", "AllQueuedTransferStruct", l)
+}
+
+func (l *AllQueuedTransferList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
+
+type OpResultList []OpResult
+
+func (l OpResultList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "cwmp:OpResultStruct", "OpResultStruct", l)
+}
+
+func (l *OpResultList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
+
+type AutonOpResultList []AutonOpResult
+
+func (l AutonOpResultList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "cwmp:AutonOpResultStruct", "AutonOpResultStruct", l)
+}
+
+func (l *AutonOpResultList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
+
+type ArgList []Arg
+
+func (l ArgList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeArray(e, start, "cwmp:ArgStruct", "ArgStruct", l)
+}
+
+func (l *ArgList) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeArray(d, start, l)
+}
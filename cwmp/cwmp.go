@@ -2,8 +2,8 @@ package cwmp
 
 import (
 	"encoding/xml"
+	"fmt"
 	"strings"
-	"time"
 
 	"github.com/scottlangendyk/go-cwmp/soap"
 )
@@ -31,7 +31,59 @@ const (
 	CPENotificationRequestRejected = 9009
 	CPEFileTransferFailure         = 9010
 	CPEUploadFailure               = 9011
+	CPEFileTransferAuthFailure     = 9012
+	CPEUnsupportedProtocol         = 9013
+	CPEMulticastJoinFailure        = 9014
+	CPEFileServerUnreachable       = 9015
+	CPEFileAccessFailure           = 9016
+	CPEDownloadIncomplete          = 9017
+	CPEFileCorrupted               = 9018
+	CPEFileAuthFailure             = 9019
+	CPETimeWindowsExceeded         = 9020
+	CPECancelNotPermitted          = 9021
 	CPEInvalidUUID                 = 9022
+	CPEUnknownExecutionEnv         = 9023
+	CPEDisabledExecutionEnv        = 9024
+	CPEExecutionEnvMismatch        = 9025
+	CPEDuplicateDeploymentUnit     = 9026
+	CPESystemResourcesExceeded     = 9027
+	CPEUnknownDeploymentUnit       = 9028
+	CPEInvalidDeploymentUnitState  = 9029
+	CPEDowngradeNotPermitted       = 9030
+	CPEVersionNotSpecified         = 9031
+	CPEVersionExists               = 9032
+
+	CPEResourcesExceeded = CPEResourcedExceeded
+)
+
+const (
+	UploadFileTypeVendorConfiguration = "1 Vendor Configuration File"
+	UploadFileTypeVendorLog           = "2 Vendor Log File"
+)
+
+const (
+	WindowModeAtAnyTime          = "1 At Any Time"
+	WindowModeImmediately        = "2 Immediately"
+	WindowModeWhenIdle           = "3 When Idle"
+	WindowModeConfirmationNeeded = "4 Confirmation Needed"
+)
+
+const (
+	TransferStateNotStarted = 1
+	TransferStateInProgress = 2
+	TransferStateCompleted  = 3
+)
+
+const (
+	DUStateInstalled   = "Installed"
+	DUStateUninstalled = "Uninstalled"
+	DUStateFailed      = "Failed"
+)
+
+const (
+	NotificationOff     = 0
+	NotificationPassive = 1
+	NotificationActive  = 2
 )
 
 type CWMPVersions []string
@@ -59,6 +111,10 @@ type Header struct {
 	SessionTimeout        *uint
 	SupportedCWMPVersions *CWMPVersions
 	UseCWMPVersion        *string
+
+	// Namespace is the CWMP namespace the envelope was decoded from. It is
+	// not encoded, see NewEncoder.
+	Namespace string
 }
 
 func (h Header) startElement(local string, mustUnderstand bool) xml.StartElement {
@@ -168,12 +224,15 @@ func Decode(d *xml.Decoder) (*soap.Envelope, error) {
 		Body:   b,
 	}
 
-	err := d.Decode(e)
+	r := &namespaceReader{d: d}
+
+	err := xml.NewTokenDecoder(r).Decode(e)
 	if err != nil {
 		return nil, err
 	}
 
 	e.Body = b.Contents
+	h.Namespace = r.namespace
 
 	return e, nil
 }
@@ -192,44 +251,11 @@ func (b *body) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 		b.Contents = &soap.Fault{
 			Detail: &Fault{},
 		}
-	case "Inform":
-		b.Contents = &Inform{}
-	case "InformRespone":
-		b.Contents = &InformResponse{}
-	case "GetRPCMethods":
-		b.Contents = &GetRPCMethods{}
-	case "GetRPCMethodsResponse":
-		b.Contents = &GetRPCMethodsResponse{}
-	case "Reboot":
-		b.Contents = &Reboot{}
-	case "RebootResponse":
-		b.Contents = &RebootResponse{}
-	case "TransferComplete":
-		b.Contents = &TransferComplete{}
-	case "TransferCompleteResponse":
-		b.Contents = &TransferCompleteResponse{}
-	case "AutonomousTransferComplete":
-		b.Contents = &AutonomousTransferComplete{}
-	case "AutonomousTransferCompleteResponse":
-		b.Contents = &AutonomousTransferCompleteResponse{}
-	case "Download":
-		b.Contents = &Download{}
-	case "DownloadResponse":
-		b.Contents = &DownloadResponse{}
-	case "GetParameterValues":
-		b.Contents = &GetParameterValues{}
-	case "GetParameterValuesResponse":
-		b.Contents = &GetParameterValuesResponse{}
-	case "SetParameterValues":
-		b.Contents = &SetParameterValues{}
-	case "SetParameterValuesResponse":
-		b.Contents = &SetParameterValuesResponse{}
-	case "GetParameterNames":
-		b.Contents = &GetParameterNames{}
-	case "GetParameterNamesResponse":
-		b.Contents = &GetParameterNamesResponse{}
 	default:
-		return d.Skip()
+		b.Contents = newElement(start.Name.Local)
+		if b.Contents == nil {
+			b.Contents = &Unknown{}
+		}
 	}
 
 	return d.DecodeElement(&b.Contents, &start)
@@ -244,13 +270,31 @@ type RebootResponse struct {
 	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 RebootResponse"`
 }
 
+type FactoryReset struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 FactoryReset"`
+}
+
+type FactoryResetResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 FactoryResetResponse"`
+}
+
+type ScheduleInform struct {
+	XMLName      xml.Name `xml:"urn:dslforum-org:cwmp-1-0 ScheduleInform"`
+	DelaySeconds uint
+	CommandKey   string
+}
+
+type ScheduleInformResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 ScheduleInformResponse"`
+}
+
 type GetRPCMethods struct {
 	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 GetRPCMethods"`
 }
 
 type GetRPCMethodsResponse struct {
 	XMLName    xml.Name `xml:"urn:dslforum-org:cwmp-1-0 GetRPCMethodsResponse"`
-	MethodList []string `xml:"MethodList>string"`
+	MethodList StringList
 }
 
 type Fault struct {
@@ -260,12 +304,17 @@ type Fault struct {
 	SetParameterValuesFault []SetParameterValuesFault
 }
 
+type FaultStruct struct {
+	Code   uint   `xml:"FaultCode"`
+	String string `xml:"FaultString"`
+}
+
 type TransferComplete struct {
 	XMLName      xml.Name `xml:"urn:dslforum-org:cwmp-1-0 TransferComplete"`
 	CommandKey   string
-	Fault        Fault `xml:"FaultStruct"`
-	StartTime    time.Time
-	CompleteTime time.Time
+	Fault        FaultStruct `xml:"FaultStruct"`
+	StartTime    DateTime
+	CompleteTime DateTime
 }
 
 type TransferCompleteResponse struct {
@@ -280,15 +329,150 @@ type AutonomousTransferComplete struct {
 	FileType       string
 	FileSize       uint
 	TargetFileName string
-	Fault          Fault `xml:"FaultStruct"`
-	StartTime      time.Time
-	CompleteTime   time.Time
+	Fault          FaultStruct `xml:"FaultStruct"`
+	StartTime      DateTime
+	CompleteTime   DateTime
 }
 
 type AutonomousTransferCompleteResponse struct {
 	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 AutonomousTransferCompleteResponse"`
 }
 
+type Operation interface {
+	operation()
+}
+
+type InstallOp struct {
+	XMLName         xml.Name `xml:"InstallOpStruct"`
+	URL             string
+	UUID            string
+	Username        string
+	Password        string
+	ExecutionEnvRef string
+}
+
+func (InstallOp) operation() {}
+
+type UpdateOp struct {
+	XMLName  xml.Name `xml:"UpdateOpStruct"`
+	UUID     string
+	Version  string
+	URL      string
+	Username string
+	Password string
+}
+
+func (UpdateOp) operation() {}
+
+type UninstallOp struct {
+	XMLName         xml.Name `xml:"UninstallOpStruct"`
+	UUID            string
+	Version         string
+	ExecutionEnvRef string
+}
+
+func (UninstallOp) operation() {}
+
+type Operations []Operation
+
+func (o Operations) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr, arrayTypeAttr("cwmp:OperationStruct", len(o)))
+
+	err := e.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	for _, op := range o {
+		err = e.Encode(op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+func (o *Operations) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch el := t.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			var op Operation
+
+			switch el.Name.Local {
+			case "InstallOpStruct":
+				op = &InstallOp{}
+			case "UpdateOpStruct":
+				op = &UpdateOp{}
+			case "UninstallOpStruct":
+				op = &UninstallOp{}
+			default:
+				return fmt.Errorf("cwmp: Unknown operation (%s)", el.Name.Local)
+			}
+
+			err = d.DecodeElement(op, &el)
+			if err != nil {
+				return err
+			}
+
+			*o = append(*o, op)
+		}
+	}
+}
+
+type ChangeDUState struct {
+	XMLName    xml.Name `xml:"urn:dslforum-org:cwmp-1-0 ChangeDUState"`
+	Operations Operations
+	CommandKey string
+}
+
+type ChangeDUStateResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 ChangeDUStateResponse"`
+}
+
+type OpResult struct {
+	UUID                 string
+	DeploymentUnitRef    string
+	Version              string
+	CurrentState         string
+	Resolved             bool
+	ExecutionUnitRefList string
+	StartTime            DateTime
+	CompleteTime         DateTime
+	Fault                FaultStruct
+}
+
+type AutonOpResult struct {
+	OpResult
+	OperationPerformed string
+}
+
+type DUStateChangeComplete struct {
+	XMLName    xml.Name `xml:"urn:dslforum-org:cwmp-1-0 DUStateChangeComplete"`
+	Results    OpResultList
+	CommandKey string
+}
+
+type DUStateChangeCompleteResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 DUStateChangeCompleteResponse"`
+}
+
+type AutonomousDUStateChangeComplete struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 AutonomousDUStateChangeComplete"`
+	Results AutonOpResultList
+}
+
+type AutonomousDUStateChangeCompleteResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 AutonomousDUStateChangeCompleteResponse"`
+}
+
 type Download struct {
 	XMLName        xml.Name `xml:"urn:dslforum-org:cwmp-1-0 Download"`
 	CommandKey     string
@@ -306,8 +490,118 @@ type Download struct {
 type DownloadResponse struct {
 	XMLName      xml.Name `xml:"urn:dslforum-org:cwmp-1-0 DownloadResponse"`
 	Completed    bool
-	StartTime    time.Time
-	CompleteTime time.Time
+	StartTime    DateTime
+	CompleteTime DateTime
+}
+
+type TimeWindow struct {
+	WindowStart uint
+	WindowEnd   uint
+	WindowMode  string
+	UserMessage string
+	MaxRetries  int
+}
+
+type ScheduleDownload struct {
+	XMLName        xml.Name `xml:"urn:dslforum-org:cwmp-1-0 ScheduleDownload"`
+	CommandKey     string
+	FileType       string
+	URL            string
+	Username       string
+	Password       string
+	FileSize       uint
+	TargetFileName string
+	TimeWindowList TimeWindowList
+}
+
+type ScheduleDownloadResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 ScheduleDownloadResponse"`
+}
+
+type CancelTransfer struct {
+	XMLName    xml.Name `xml:"urn:dslforum-org:cwmp-1-0 CancelTransfer"`
+	CommandKey string
+}
+
+type CancelTransferResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 CancelTransferResponse"`
+}
+
+type GetQueuedTransfers struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 GetQueuedTransfers"`
+}
+
+type QueuedTransfer struct {
+	CommandKey string
+	State      int
+}
+
+type GetQueuedTransfersResponse struct {
+	XMLName      xml.Name `xml:"urn:dslforum-org:cwmp-1-0 GetQueuedTransfersResponse"`
+	TransferList QueuedTransferList
+}
+
+type GetAllQueuedTransfers struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 GetAllQueuedTransfers"`
+}
+
+type AllQueuedTransfer struct {
+	CommandKey     string
+	State          int
+	IsDownload     bool
+	FileType       string
+	FileSize       uint
+	TargetFileName string
+}
+
+type GetAllQueuedTransfersResponse struct {
+	XMLName      xml.Name `xml:"urn:dslforum-org:cwmp-1-0 GetAllQueuedTransfersResponse"`
+	TransferList AllQueuedTransferList
+}
+
+type Upload struct {
+	XMLName      xml.Name `xml:"urn:dslforum-org:cwmp-1-0 Upload"`
+	CommandKey   string
+	FileType     string
+	URL          string
+	Username     string
+	Password     string
+	DelaySeconds uint
+}
+
+type UploadResponse struct {
+	XMLName      xml.Name `xml:"urn:dslforum-org:cwmp-1-0 UploadResponse"`
+	Status       int
+	StartTime    DateTime
+	CompleteTime DateTime
+}
+
+type Arg struct {
+	Name  string
+	Value string
+}
+
+type RequestDownload struct {
+	XMLName     xml.Name `xml:"urn:dslforum-org:cwmp-1-0 RequestDownload"`
+	FileType    string
+	FileTypeArg ArgList
+}
+
+type RequestDownloadResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 RequestDownloadResponse"`
+}
+
+type Kicked struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 Kicked"`
+	Command string
+	Referer string
+	Arg     string
+	Next    string
+}
+
+type KickedResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 KickedResponse"`
+	NextURL string
 }
 
 type GetParameterNames struct {
@@ -323,27 +617,28 @@ type ParameterInfo struct {
 
 type GetParameterNamesResponse struct {
 	XMLName       xml.Name `xml:"urn:dslforum-org:cwmp-1-0 GetParameterNamesResponse"`
-	ParameterList []ParameterInfo
+	ParameterList ParameterInfoList
 }
 
 type GetParameterValues struct {
 	XMLName        xml.Name `xml:"urn:dslforum-org:cwmp-1-0 GetParameterValues"`
-	ParameterNames string
+	ParameterNames StringList
 }
 
 type GetParameterValuesResponse struct {
 	XMLName       xml.Name `xml:"urn:dslforum-org:cwmp-1-0 GetParameterValuesResponse"`
-	ParameterList []ParameterValue
+	ParameterList ParameterValueList
 }
 
 type ParameterValue struct {
 	Name  string
 	Value string
+	Type  ValueType
 }
 
 type SetParameterValues struct {
 	XMLName       xml.Name `xml:"urn:dslforum-org:cwmp-1-0 SetParameterValues"`
-	ParameterList []ParameterValue
+	ParameterList ParameterValueList
 	ParameterKey  string
 }
 
@@ -352,6 +647,62 @@ type SetParameterValuesResponse struct {
 	Status  bool
 }
 
+type AddObject struct {
+	XMLName      xml.Name `xml:"urn:dslforum-org:cwmp-1-0 AddObject"`
+	ObjectName   string
+	ParameterKey string
+}
+
+type AddObjectResponse struct {
+	XMLName        xml.Name `xml:"urn:dslforum-org:cwmp-1-0 AddObjectResponse"`
+	InstanceNumber uint
+	Status         int
+}
+
+type DeleteObject struct {
+	XMLName      xml.Name `xml:"urn:dslforum-org:cwmp-1-0 DeleteObject"`
+	ObjectName   string
+	ParameterKey string
+}
+
+type DeleteObjectResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 DeleteObjectResponse"`
+	Status  int
+}
+
+type GetParameterAttributes struct {
+	XMLName        xml.Name `xml:"urn:dslforum-org:cwmp-1-0 GetParameterAttributes"`
+	ParameterNames StringList
+}
+
+type ParameterAttribute struct {
+	Name         string
+	Notification int
+	AccessList   StringList
+}
+
+type GetParameterAttributesResponse struct {
+	XMLName       xml.Name `xml:"urn:dslforum-org:cwmp-1-0 GetParameterAttributesResponse"`
+	ParameterList ParameterAttributeList
+}
+
+type SetParameterAttribute struct {
+	Name               string
+	NotificationChange bool
+	Notification       int
+	AccessListChange   bool
+	AccessList         StringList
+}
+
+type SetParameterAttributes struct {
+	XMLName       xml.Name `xml:"urn:dslforum-org:cwmp-1-0 SetParameterAttributes"`
+	ParameterList SetParameterAttributeList
+}
+
+type SetParameterAttributesResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 SetParameterAttributesResponse"`
+}
+
 type DeviceID struct {
 	Manufacturer string
 	OUI          string
@@ -360,18 +711,18 @@ type DeviceID struct {
 }
 
 type Event struct {
-	EventCode  string
+	EventCode  EventCode
 	CommandKey string
 }
 
 type Inform struct {
 	XMLName       xml.Name `xml:"urn:dslforum-org:cwmp-1-0 Inform"`
 	RetryCount    uint
-	CurrentTime   time.Time
+	CurrentTime   DateTime
 	MaxEnvelopes  uint
-	DeviceID      DeviceID         `xml:"DeviceId"`
-	Event         []Event          `xml:"Event>EventStruct"`
-	ParameterList []ParameterValue `xml:"ParameterList>ParameterValueStruct"`
+	DeviceID      DeviceID `xml:"DeviceId"`
+	Event         EventList
+	ParameterList ParameterValueList
 }
 
 type InformResponse struct {
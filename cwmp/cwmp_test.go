@@ -32,6 +32,25 @@ func assertEncode(t *testing.T, v interface{}, want string) {
 	assertEqual(t, want, b.String())
 }
 
+func assertRoundTrip(t *testing.T, v interface{}, want string) {
+	assertEncode(t, v, want)
+
+	input := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body>` + want + `</soapenv:Body></soapenv:Envelope>`
+
+	d := xml.NewDecoder(strings.NewReader(input))
+
+	e, err := Decode(d)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if reflect.TypeOf(v) != reflect.TypeOf(e.Body) {
+		t.Fatalf("Body types aren't equal\nwant: %T\ngot:  %T", v, e.Body)
+	}
+
+	assertEncode(t, e.Body, want)
+}
+
 func assertHeader(t *testing.T, want, got Header) {
 	assertEqual(t, want.ID, got.ID)
 	assertEqual(t, want.SessionTimeout, got.SessionTimeout)
@@ -200,7 +219,7 @@ func TestDecodeInform(t *testing.T) {
 			Event{EventCode: "2 PERIODIC"},
 		},
 		MaxEnvelopes: 1,
-		CurrentTime:  time.Date(2020, 01, 02, 20, 50, 49, 0, time.FixedZone("EST", -5*60*60)),
+		CurrentTime:  DateTime{Time: time.Date(2020, 01, 02, 20, 50, 49, 0, time.FixedZone("EST", -5*60*60))},
 		RetryCount:   0,
 		ParameterList: []ParameterValue{
 			ParameterValue{Name: "Device.RootDataModelVersion", Value: "2.11"},
@@ -326,7 +345,7 @@ func TestEncodeGetRPCMethodsResponse(t *testing.T) {
 		MethodList: []string{"Method1", "Method2"},
 	}
 
-	want := `<GetRPCMethodsResponse xmlns="urn:dslforum-org:cwmp-1-0"><MethodList><string>Method1</string><string>Method2</string></MethodList></GetRPCMethodsResponse>`
+	want := `<GetRPCMethodsResponse xmlns="urn:dslforum-org:cwmp-1-0"><MethodList xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="xsd:string[2]"><string>Method1</string><string>Method2</string></MethodList></GetRPCMethodsResponse>`
 
 	assertEncode(t, v, want)
 }
@@ -370,3 +389,443 @@ func TestEncodeHeaderPartial(t *testing.T) {
 
 	assertEncode(t, v, want)
 }
+
+func TestAddObject(t *testing.T) {
+	v := &AddObject{
+		ObjectName:   "Device.NAT.PortMapping.",
+		ParameterKey: "key1",
+	}
+
+	want := `<AddObject xmlns="urn:dslforum-org:cwmp-1-0"><ObjectName>Device.NAT.PortMapping.</ObjectName><ParameterKey>key1</ParameterKey></AddObject>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestAddObjectResponse(t *testing.T) {
+	v := &AddObjectResponse{
+		InstanceNumber: 3,
+		Status:         1,
+	}
+
+	want := `<AddObjectResponse xmlns="urn:dslforum-org:cwmp-1-0"><InstanceNumber>3</InstanceNumber><Status>1</Status></AddObjectResponse>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestDeleteObject(t *testing.T) {
+	v := &DeleteObject{
+		ObjectName:   "Device.WiFi.SSID.2.",
+		ParameterKey: "key2",
+	}
+
+	want := `<DeleteObject xmlns="urn:dslforum-org:cwmp-1-0"><ObjectName>Device.WiFi.SSID.2.</ObjectName><ParameterKey>key2</ParameterKey></DeleteObject>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestDeleteObjectResponse(t *testing.T) {
+	want := `<DeleteObjectResponse xmlns="urn:dslforum-org:cwmp-1-0"><Status>0</Status></DeleteObjectResponse>`
+
+	assertRoundTrip(t, &DeleteObjectResponse{}, want)
+}
+
+func TestGetParameterAttributes(t *testing.T) {
+	v := &GetParameterAttributes{
+		ParameterNames: []string{"Device.IP.Interface.1.IPv4Address.1.IPAddress", "Device.DeviceInfo."},
+	}
+
+	want := `<GetParameterAttributes xmlns="urn:dslforum-org:cwmp-1-0"><ParameterNames xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="xsd:string[2]"><string>Device.IP.Interface.1.IPv4Address.1.IPAddress</string><string>Device.DeviceInfo.</string></ParameterNames></GetParameterAttributes>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestGetParameterAttributesResponse(t *testing.T) {
+	v := &GetParameterAttributesResponse{
+		ParameterList: []ParameterAttribute{
+			ParameterAttribute{
+				Name:         "Device.IP.Interface.1.IPv4Address.1.IPAddress",
+				Notification: NotificationActive,
+				AccessList:   []string{"Subscriber"},
+			},
+			ParameterAttribute{
+				Name:         "Device.DeviceInfo.UpTime",
+				Notification: NotificationOff,
+			},
+		},
+	}
+
+	want := `<GetParameterAttributesResponse xmlns="urn:dslforum-org:cwmp-1-0"><ParameterList xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="cwmp:ParameterAttributeStruct[2]"><ParameterAttributeStruct><Name>Device.IP.Interface.1.IPv4Address.1.IPAddress</Name><Notification>2</Notification><AccessList encoding:arrayType="xsd:string[1]"><string>Subscriber</string></AccessList></ParameterAttributeStruct><ParameterAttributeStruct><Name>Device.DeviceInfo.UpTime</Name><Notification>0</Notification><AccessList encoding:arrayType="xsd:string[0]"></AccessList></ParameterAttributeStruct></ParameterList></GetParameterAttributesResponse>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestSetParameterAttributes(t *testing.T) {
+	v := &SetParameterAttributes{
+		ParameterList: []SetParameterAttribute{
+			SetParameterAttribute{
+				Name:               "Device.IP.Interface.1.IPv4Address.1.IPAddress",
+				NotificationChange: true,
+				Notification:       NotificationActive,
+				AccessListChange:   true,
+				AccessList:         []string{"Subscriber"},
+			},
+		},
+	}
+
+	want := `<SetParameterAttributes xmlns="urn:dslforum-org:cwmp-1-0"><ParameterList xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="cwmp:SetParameterAttributesStruct[1]"><SetParameterAttributesStruct><Name>Device.IP.Interface.1.IPv4Address.1.IPAddress</Name><NotificationChange>true</NotificationChange><Notification>2</Notification><AccessListChange>true</AccessListChange><AccessList encoding:arrayType="xsd:string[1]"><string>Subscriber</string></AccessList></SetParameterAttributesStruct></ParameterList></SetParameterAttributes>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestDecodeSetParameterAttributesNumericBooleans(t *testing.T) {
+	input := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:cwmp="urn:dslforum-org:cwmp-1-0"><soapenv:Body><cwmp:SetParameterAttributes><ParameterList><SetParameterAttributesStruct><Name>Device.DeviceInfo.SoftwareVersion</Name><NotificationChange>1</NotificationChange><Notification>1</Notification><AccessListChange>0</AccessListChange><AccessList></AccessList></SetParameterAttributesStruct></ParameterList></cwmp:SetParameterAttributes></soapenv:Body></soapenv:Envelope>`
+
+	e, err := Decode(xml.NewDecoder(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	got, ok := e.Body.(*SetParameterAttributes)
+	if !ok {
+		t.Fatal("Body is not type SetParameterAttributes")
+	}
+
+	assertEqual(t, 1, len(got.ParameterList))
+	assertEqual(t, true, got.ParameterList[0].NotificationChange)
+	assertEqual(t, NotificationPassive, got.ParameterList[0].Notification)
+	assertEqual(t, false, got.ParameterList[0].AccessListChange)
+	assertEqual(t, 0, len(got.ParameterList[0].AccessList))
+}
+
+func TestSetParameterAttributesResponse(t *testing.T) {
+	want := `<SetParameterAttributesResponse xmlns="urn:dslforum-org:cwmp-1-0"></SetParameterAttributesResponse>`
+
+	assertRoundTrip(t, &SetParameterAttributesResponse{}, want)
+}
+
+func TestUpload(t *testing.T) {
+	v := &Upload{
+		CommandKey:   "log-1",
+		FileType:     UploadFileTypeVendorLog,
+		URL:          "http://acs.example.com/upload/E48D8C-B7B20A1DE3F0/log-1",
+		DelaySeconds: 5,
+	}
+
+	want := `<Upload xmlns="urn:dslforum-org:cwmp-1-0"><CommandKey>log-1</CommandKey><FileType>2 Vendor Log File</FileType><URL>http://acs.example.com/upload/E48D8C-B7B20A1DE3F0/log-1</URL><Username></Username><Password></Password><DelaySeconds>5</DelaySeconds></Upload>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestUploadResponse(t *testing.T) {
+	v := &UploadResponse{
+		Status:       0,
+		StartTime:    DateTime{Time: time.Date(2020, 01, 02, 20, 50, 49, 0, time.UTC)},
+		CompleteTime: DateTime{Time: time.Date(2020, 01, 02, 20, 50, 52, 0, time.UTC)},
+	}
+
+	want := `<UploadResponse xmlns="urn:dslforum-org:cwmp-1-0"><Status>0</Status><StartTime>2020-01-02T20:50:49Z</StartTime><CompleteTime>2020-01-02T20:50:52Z</CompleteTime></UploadResponse>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestFactoryReset(t *testing.T) {
+	assertRoundTrip(t, &FactoryReset{}, `<FactoryReset xmlns="urn:dslforum-org:cwmp-1-0"></FactoryReset>`)
+	assertRoundTrip(t, &FactoryResetResponse{}, `<FactoryResetResponse xmlns="urn:dslforum-org:cwmp-1-0"></FactoryResetResponse>`)
+}
+
+func TestScheduleInform(t *testing.T) {
+	v := &ScheduleInform{
+		DelaySeconds: 30,
+		CommandKey:   "callback",
+	}
+
+	want := `<ScheduleInform xmlns="urn:dslforum-org:cwmp-1-0"><DelaySeconds>30</DelaySeconds><CommandKey>callback</CommandKey></ScheduleInform>`
+
+	assertRoundTrip(t, v, want)
+	assertRoundTrip(t, &ScheduleInformResponse{}, `<ScheduleInformResponse xmlns="urn:dslforum-org:cwmp-1-0"></ScheduleInformResponse>`)
+}
+
+func TestScheduleDownload(t *testing.T) {
+	v := &ScheduleDownload{
+		CommandKey: "fw-6.47",
+		FileType:   "1 Firmware Upgrade Image",
+		URL:        "http://files.example.com/fw.npk",
+		FileSize:   1024,
+		TimeWindowList: []TimeWindow{
+			TimeWindow{
+				WindowStart: 0,
+				WindowEnd:   3600,
+				WindowMode:  WindowModeWhenIdle,
+				MaxRetries:  -1,
+			},
+			TimeWindow{
+				WindowStart: 86400,
+				WindowEnd:   90000,
+				WindowMode:  WindowModeConfirmationNeeded,
+				UserMessage: "Upgrade now?",
+				MaxRetries:  2,
+			},
+		},
+	}
+
+	want := `<ScheduleDownload xmlns="urn:dslforum-org:cwmp-1-0"><CommandKey>fw-6.47</CommandKey><FileType>1 Firmware Upgrade Image</FileType><URL>http://files.example.com/fw.npk</URL><Username></Username><Password></Password><FileSize>1024</FileSize><TargetFileName></TargetFileName><TimeWindowList xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="cwmp:TimeWindowStruct[2]"><TimeWindowStruct><WindowStart>0</WindowStart><WindowEnd>3600</WindowEnd><WindowMode>3 When Idle</WindowMode><UserMessage></UserMessage><MaxRetries>-1</MaxRetries></TimeWindowStruct><TimeWindowStruct><WindowStart>86400</WindowStart><WindowEnd>90000</WindowEnd><WindowMode>4 Confirmation Needed</WindowMode><UserMessage>Upgrade now?</UserMessage><MaxRetries>2</MaxRetries></TimeWindowStruct></TimeWindowList></ScheduleDownload>`
+
+	assertRoundTrip(t, v, want)
+	assertRoundTrip(t, &ScheduleDownloadResponse{}, `<ScheduleDownloadResponse xmlns="urn:dslforum-org:cwmp-1-0"></ScheduleDownloadResponse>`)
+}
+
+func TestCancelTransfer(t *testing.T) {
+	assertRoundTrip(t, &CancelTransfer{CommandKey: "fw-6.47"}, `<CancelTransfer xmlns="urn:dslforum-org:cwmp-1-0"><CommandKey>fw-6.47</CommandKey></CancelTransfer>`)
+	assertRoundTrip(t, &CancelTransferResponse{}, `<CancelTransferResponse xmlns="urn:dslforum-org:cwmp-1-0"></CancelTransferResponse>`)
+}
+
+func TestGetQueuedTransfers(t *testing.T) {
+	assertRoundTrip(t, &GetQueuedTransfers{}, `<GetQueuedTransfers xmlns="urn:dslforum-org:cwmp-1-0"></GetQueuedTransfers>`)
+
+	v := &GetQueuedTransfersResponse{
+		TransferList: []QueuedTransfer{
+			QueuedTransfer{CommandKey: "fw-6.47", State: TransferStateNotStarted},
+			QueuedTransfer{CommandKey: "cfg", State: TransferStateInProgress},
+		},
+	}
+
+	want := `<GetQueuedTransfersResponse xmlns="urn:dslforum-org:cwmp-1-0"><TransferList xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="cwmp:QueuedTransferStruct[2]"><QueuedTransferStruct><CommandKey>fw-6.47</CommandKey><State>1</State></QueuedTransferStruct><QueuedTransferStruct><CommandKey>cfg</CommandKey><State>2</State></QueuedTransferStruct></TransferList></GetQueuedTransfersResponse>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestGetAllQueuedTransfers(t *testing.T) {
+	assertRoundTrip(t, &GetAllQueuedTransfers{}, `<GetAllQueuedTransfers xmlns="urn:dslforum-org:cwmp-1-0"></GetAllQueuedTransfers>`)
+
+	v := &GetAllQueuedTransfersResponse{
+		TransferList: []AllQueuedTransfer{
+			AllQueuedTransfer{
+				CommandKey:     "fw-6.47",
+				State:          TransferStateNotStarted,
+				IsDownload:     true,
+				FileType:       "1 Firmware Upgrade Image",
+				FileSize:       1024,
+				TargetFileName: "fw.npk",
+			},
+		},
+	}
+
+	want := `<GetAllQueuedTransfersResponse xmlns="urn:dslforum-org:cwmp-1-0"><TransferList xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="cwmp:AllQueuedTransferStruct[1]"><AllQueuedTransferStruct><CommandKey>fw-6.47</CommandKey><State>1</State><IsDownload>true</IsDownload><FileType>1 Firmware Upgrade Image</FileType><FileSize>1024</FileSize><TargetFileName>fw.npk</TargetFileName></AllQueuedTransferStruct></TransferList></GetAllQueuedTransfersResponse>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestTransferComplete(t *testing.T) {
+	v := &TransferComplete{
+		CommandKey: "fw-6.47",
+		Fault: FaultStruct{
+			Code:   CPEFileTransferFailure,
+			String: "Download failed",
+		},
+		StartTime:    DateTime{Time: time.Date(2020, 01, 02, 20, 50, 49, 0, time.UTC)},
+		CompleteTime: DateTime{Time: time.Date(2020, 01, 02, 20, 51, 49, 0, time.UTC)},
+	}
+
+	want := `<TransferComplete xmlns="urn:dslforum-org:cwmp-1-0"><CommandKey>fw-6.47</CommandKey><FaultStruct><FaultCode>9010</FaultCode><FaultString>Download failed</FaultString></FaultStruct><StartTime>2020-01-02T20:50:49Z</StartTime><CompleteTime>2020-01-02T20:51:49Z</CompleteTime></TransferComplete>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestChangeDUState(t *testing.T) {
+	v := &ChangeDUState{
+		Operations: Operations{
+			InstallOp{
+				URL:             "http://apps.example.com/app.ipk",
+				UUID:            "6b6f6e69-6b61-5a5b-9c9d-000000000001",
+				ExecutionEnvRef: "Device.SoftwareModules.ExecEnv.1",
+			},
+			UpdateOp{
+				UUID:    "6b6f6e69-6b61-5a5b-9c9d-000000000002",
+				Version: "2.0",
+			},
+			UninstallOp{
+				UUID: "6b6f6e69-6b61-5a5b-9c9d-000000000003",
+			},
+		},
+		CommandKey: "apps",
+	}
+
+	want := `<ChangeDUState xmlns="urn:dslforum-org:cwmp-1-0"><Operations xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="cwmp:OperationStruct[3]"><InstallOpStruct><URL>http://apps.example.com/app.ipk</URL><UUID>6b6f6e69-6b61-5a5b-9c9d-000000000001</UUID><Username></Username><Password></Password><ExecutionEnvRef>Device.SoftwareModules.ExecEnv.1</ExecutionEnvRef></InstallOpStruct><UpdateOpStruct><UUID>6b6f6e69-6b61-5a5b-9c9d-000000000002</UUID><Version>2.0</Version><URL></URL><Username></Username><Password></Password></UpdateOpStruct><UninstallOpStruct><UUID>6b6f6e69-6b61-5a5b-9c9d-000000000003</UUID><Version></Version><ExecutionEnvRef></ExecutionEnvRef></UninstallOpStruct></Operations><CommandKey>apps</CommandKey></ChangeDUState>`
+
+	assertRoundTrip(t, v, want)
+	assertRoundTrip(t, &ChangeDUStateResponse{}, `<ChangeDUStateResponse xmlns="urn:dslforum-org:cwmp-1-0"></ChangeDUStateResponse>`)
+}
+
+func TestDUStateChangeComplete(t *testing.T) {
+	v := &DUStateChangeComplete{
+		Results: []OpResult{
+			OpResult{
+				UUID:                 "6b6f6e69-6b61-5a5b-9c9d-000000000001",
+				DeploymentUnitRef:    "Device.SoftwareModules.DeploymentUnit.1",
+				Version:              "1.0",
+				CurrentState:         DUStateInstalled,
+				Resolved:             true,
+				ExecutionUnitRefList: "Device.SoftwareModules.ExecutionUnit.1",
+				StartTime:            DateTime{Time: time.Date(2020, 01, 02, 20, 50, 49, 0, time.UTC)},
+				CompleteTime:         DateTime{Time: time.Date(2020, 01, 02, 20, 51, 49, 0, time.UTC)},
+			},
+		},
+		CommandKey: "apps",
+	}
+
+	want := `<DUStateChangeComplete xmlns="urn:dslforum-org:cwmp-1-0"><Results xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="cwmp:OpResultStruct[1]"><OpResultStruct><UUID>6b6f6e69-6b61-5a5b-9c9d-000000000001</UUID><DeploymentUnitRef>Device.SoftwareModules.DeploymentUnit.1</DeploymentUnitRef><Version>1.0</Version><CurrentState>Installed</CurrentState><Resolved>true</Resolved><ExecutionUnitRefList>Device.SoftwareModules.ExecutionUnit.1</ExecutionUnitRefList><StartTime>2020-01-02T20:50:49Z</StartTime><CompleteTime>2020-01-02T20:51:49Z</CompleteTime><Fault><FaultCode>0</FaultCode><FaultString></FaultString></Fault></OpResultStruct></Results><CommandKey>apps</CommandKey></DUStateChangeComplete>`
+
+	assertRoundTrip(t, v, want)
+	assertRoundTrip(t, &DUStateChangeCompleteResponse{}, `<DUStateChangeCompleteResponse xmlns="urn:dslforum-org:cwmp-1-0"></DUStateChangeCompleteResponse>`)
+}
+
+func TestAutonomousDUStateChangeComplete(t *testing.T) {
+	v := &AutonomousDUStateChangeComplete{
+		Results: []AutonOpResult{
+			AutonOpResult{
+				OpResult: OpResult{
+					UUID:         "6b6f6e69-6b61-5a5b-9c9d-000000000001",
+					CurrentState: DUStateUninstalled,
+					StartTime:    DateTime{Time: time.Date(2020, 01, 02, 20, 50, 49, 0, time.UTC)},
+					CompleteTime: DateTime{Time: time.Date(2020, 01, 02, 20, 51, 49, 0, time.UTC)},
+				},
+				OperationPerformed: "Uninstall",
+			},
+		},
+	}
+
+	want := `<AutonomousDUStateChangeComplete xmlns="urn:dslforum-org:cwmp-1-0"><Results xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="cwmp:AutonOpResultStruct[1]"><AutonOpResultStruct><UUID>6b6f6e69-6b61-5a5b-9c9d-000000000001</UUID><DeploymentUnitRef></DeploymentUnitRef><Version></Version><CurrentState>Uninstalled</CurrentState><Resolved>false</Resolved><ExecutionUnitRefList></ExecutionUnitRefList><StartTime>2020-01-02T20:50:49Z</StartTime><CompleteTime>2020-01-02T20:51:49Z</CompleteTime><Fault><FaultCode>0</FaultCode><FaultString></FaultString></Fault><OperationPerformed>Uninstall</OperationPerformed></AutonOpResultStruct></Results></AutonomousDUStateChangeComplete>`
+
+	assertRoundTrip(t, v, want)
+	assertRoundTrip(t, &AutonomousDUStateChangeCompleteResponse{}, `<AutonomousDUStateChangeCompleteResponse xmlns="urn:dslforum-org:cwmp-1-0"></AutonomousDUStateChangeCompleteResponse>`)
+}
+
+func TestRequestDownload(t *testing.T) {
+	v := &RequestDownload{
+		FileType: "1 Firmware Upgrade Image",
+		FileTypeArg: []Arg{
+			Arg{Name: "Version", Value: "6.47"},
+		},
+	}
+
+	want := `<RequestDownload xmlns="urn:dslforum-org:cwmp-1-0"><FileType>1 Firmware Upgrade Image</FileType><FileTypeArg xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="cwmp:ArgStruct[1]"><ArgStruct><Name>Version</Name><Value>6.47</Value></ArgStruct></FileTypeArg></RequestDownload>`
+
+	assertRoundTrip(t, v, want)
+	assertRoundTrip(t, &RequestDownloadResponse{}, `<RequestDownloadResponse xmlns="urn:dslforum-org:cwmp-1-0"></RequestDownloadResponse>`)
+}
+
+func TestKicked(t *testing.T) {
+	v := &Kicked{
+		Command: "register",
+		Referer: "http://portal.example.com/",
+		Arg:     "account=1234",
+		Next:    "http://portal.example.com/done",
+	}
+
+	want := `<Kicked xmlns="urn:dslforum-org:cwmp-1-0"><Command>register</Command><Referer>http://portal.example.com/</Referer><Arg>account=1234</Arg><Next>http://portal.example.com/done</Next></Kicked>`
+
+	assertRoundTrip(t, v, want)
+	assertRoundTrip(t, &KickedResponse{NextURL: "http://portal.example.com/welcome"}, `<KickedResponse xmlns="urn:dslforum-org:cwmp-1-0"><NextURL>http://portal.example.com/welcome</NextURL></KickedResponse>`)
+}
+
+func TestDecodeArrayWithoutArrayType(t *testing.T) {
+	input := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:cwmp="urn:dslforum-org:cwmp-1-0"><soapenv:Body><cwmp:GetRPCMethodsResponse><MethodList><string>Inform</string><string>TransferComplete</string></MethodList></cwmp:GetRPCMethodsResponse></soapenv:Body></soapenv:Envelope>`
+
+	e, err := Decode(xml.NewDecoder(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	got, ok := e.Body.(*GetRPCMethodsResponse)
+	if !ok {
+		t.Fatal("Body is not type GetRPCMethodsResponse")
+	}
+
+	assertEqual(t, StringList{"Inform", "TransferComplete"}, got.MethodList)
+}
+
+func TestEncodeInformArrays(t *testing.T) {
+	v := &Inform{
+		Event: []Event{
+			Event{EventCode: "1 BOOT"},
+		},
+		ParameterList: []ParameterValue{
+			StringValue("Device.DeviceInfo.SoftwareVersion", "6.46.1"),
+		},
+	}
+
+	var b bytes.Buffer
+
+	err := xml.NewEncoder(&b).Encode(v)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, want := range []string{`arrayType="cwmp:EventStruct[1]"`, `arrayType="cwmp:ParameterValueStruct[1]"`} {
+		if !strings.Contains(b.String(), want) {
+			t.Fatalf("Expected (%s) in (%s)", want, b.String())
+		}
+	}
+}
+
+func TestGetParameterValues(t *testing.T) {
+	v := &GetParameterValues{
+		ParameterNames: []string{"Device.DeviceInfo.SoftwareVersion", "Device.WiFi.SSID."},
+	}
+
+	want := `<GetParameterValues xmlns="urn:dslforum-org:cwmp-1-0"><ParameterNames xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="xsd:string[2]"><string>Device.DeviceInfo.SoftwareVersion</string><string>Device.WiFi.SSID.</string></ParameterNames></GetParameterValues>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestDecodeGetParameterValues(t *testing.T) {
+	f, err := os.Open("testdata/getparametervalues.xml")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	e, err := Decode(xml.NewDecoder(f))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	got, ok := e.Body.(*GetParameterValues)
+	if !ok {
+		t.Fatal("Body is not type GetParameterValues")
+	}
+
+	want := StringList{
+		"Device.DeviceInfo.SoftwareVersion",
+		"Device.ManagementServer.PeriodicInformInterval",
+		"Device.WiFi.SSID.",
+	}
+
+	assertEqual(t, want, got.ParameterNames)
+}
+
+func TestDecodeGetParameterValuesResponse(t *testing.T) {
+	f, err := os.Open("testdata/getparametervaluesresponse.xml")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	e, err := Decode(xml.NewDecoder(f))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	got, ok := e.Body.(*GetParameterValuesResponse)
+	if !ok {
+		t.Fatal("Body is not type GetParameterValuesResponse")
+	}
+
+	want := ParameterValueList{
+		ParameterValue{Name: "Device.DeviceInfo.SoftwareVersion", Value: "6.46.1", Type: TypeString},
+		ParameterValue{Name: "Device.ManagementServer.PeriodicInformInterval", Value: "300", Type: TypeUnsignedInt},
+		ParameterValue{Name: "Device.WiFi.SSID.1.SSID", Value: "MikroTik-1DE3F0", Type: TypeString},
+	}
+
+	assertEqual(t, want, got.ParameterList)
+}
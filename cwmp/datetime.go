@@ -0,0 +1,113 @@
+package cwmp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UnknownTime is the dateTime a CPE reports when it doesn't know the time.
+const UnknownTime = "0001-01-01T00:00:00Z"
+
+var dateTimeLayouts = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+}
+
+// DateTime is a CWMP dateTime. It parses the variants CPEs send in practice:
+// with or without a zone offset, with +hhmm offsets and with fractional
+// seconds. Times without an offset are taken to be UTC.
+//
+// The zero value is the Unknown Time. Unset is true if the element was present
+// but empty, and it is encoded empty again.
+type DateTime struct {
+	time.Time
+	Unset bool
+}
+
+// ParseDateTime parses s as a CWMP dateTime.
+func ParseDateTime(s string) (DateTime, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return DateTime{Unset: true}, nil
+	}
+
+	for _, layout := range dateTimeLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return DateTime{Time: t}, nil
+		}
+	}
+
+	return DateTime{}, fmt.Errorf("cwmp: Invalid dateTime (%s)", s)
+}
+
+// IsUnknown reports whether d is the Unknown Time, or unset.
+func (d DateTime) IsUnknown() bool {
+	return d.Time.IsZero()
+}
+
+// IsRelative reports whether d is a time relative to the CPE's boot, which
+// CPEs without an absolute time report as a date before the year 1000.
+func (d DateTime) IsRelative() bool {
+	return !d.IsUnknown() && d.Time.UTC().Year() < 1000
+}
+
+// String returns d in canonical form, in UTC with fractional seconds only if
+// there are any.
+func (d DateTime) String() string {
+	if d.IsUnknown() {
+		return UnknownTime
+	}
+
+	return d.Time.UTC().Format(time.RFC3339Nano)
+}
+
+func (d DateTime) MarshalText() ([]byte, error) {
+	if d.Unset {
+		return []byte{}, nil
+	}
+
+	return []byte(d.String()), nil
+}
+
+func (d *DateTime) UnmarshalText(b []byte) error {
+	v, err := ParseDateTime(string(b))
+	if err != nil {
+		return err
+	}
+
+	*d = v
+
+	return nil
+}
+
+// MarshalJSON encodes d as a JSON string of its text form, rather than using
+// the method of the embedded time.Time.
+func (d DateTime) MarshalJSON() ([]byte, error) {
+	b, err := d.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(string(b))
+}
+
+func (d *DateTime) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+
+	var s string
+
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+
+	return d.UnmarshalText([]byte(s))
+}
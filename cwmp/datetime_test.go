@@ -0,0 +1,154 @@
+package cwmp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseDateTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2020-01-02T20:50:49Z", time.Date(2020, 01, 02, 20, 50, 49, 0, time.UTC)},
+		{"2020-01-02T20:50:49-05:00", time.Date(2020, 01, 03, 01, 50, 49, 0, time.UTC)},
+		{"2020-01-02T20:50:49+0100", time.Date(2020, 01, 02, 19, 50, 49, 0, time.UTC)},
+		{"2020-01-02T20:50:49", time.Date(2020, 01, 02, 20, 50, 49, 0, time.UTC)},
+		{"2020-01-02T20:50:49.123", time.Date(2020, 01, 02, 20, 50, 49, 123000000, time.UTC)},
+		{"2020-01-02T20:50:49.5Z", time.Date(2020, 01, 02, 20, 50, 49, 500000000, time.UTC)},
+		{"2020-01-02T20:50:49.250+02:00", time.Date(2020, 01, 02, 18, 50, 49, 250000000, time.UTC)},
+		{"2020-01-02 20:50:49", time.Date(2020, 01, 02, 20, 50, 49, 0, time.UTC)},
+		{" 2020-01-02T20:50:49Z\n", time.Date(2020, 01, 02, 20, 50, 49, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseDateTime(tt.in)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if !d.Equal(tt.want) {
+			t.Errorf("Parse (%s) want (%s) got (%s)", tt.in, tt.want, d.Time)
+		}
+	}
+
+	_, err := ParseDateTime("yesterday")
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+}
+
+func TestDateTimeUnknown(t *testing.T) {
+	for _, in := range []string{UnknownTime, "0001-01-01T00:00:00"} {
+		d, err := ParseDateTime(in)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		assertEqual(t, true, d.IsUnknown())
+		assertEqual(t, false, d.Unset)
+		assertEqual(t, UnknownTime, d.String())
+	}
+
+	assertEqual(t, UnknownTime, DateTime{}.String())
+
+	d, err := ParseDateTime("")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertEqual(t, true, d.Unset)
+	assertEqual(t, true, d.IsUnknown())
+}
+
+func TestDateTimeRelative(t *testing.T) {
+	d, err := ParseDateTime("0001-01-01T00:10:00Z")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertEqual(t, true, d.IsRelative())
+	assertEqual(t, false, d.IsUnknown())
+
+	assertEqual(t, false, DateTime{}.IsRelative())
+	assertEqual(t, false, DateTime{Time: time.Date(2020, 01, 02, 20, 50, 49, 0, time.UTC)}.IsRelative())
+}
+
+func TestDateTimeCanonical(t *testing.T) {
+	d := DateTime{Time: time.Date(2020, 01, 02, 20, 50, 49, 0, time.FixedZone("EST", -5*60*60))}
+	assertEqual(t, "2020-01-03T01:50:49Z", d.String())
+
+	d = DateTime{Time: time.Date(2020, 01, 02, 20, 50, 49, 120000000, time.UTC)}
+	assertEqual(t, "2020-01-02T20:50:49.12Z", d.String())
+}
+
+func TestTransferCompleteUnknownTime(t *testing.T) {
+	assertRoundTrip(t, &TransferComplete{CommandKey: "fw"}, `<TransferComplete xmlns="urn:dslforum-org:cwmp-1-0"><CommandKey>fw</CommandKey><FaultStruct><FaultCode>0</FaultCode><FaultString></FaultString></FaultStruct><StartTime>0001-01-01T00:00:00Z</StartTime><CompleteTime>0001-01-01T00:00:00Z</CompleteTime></TransferComplete>`)
+}
+
+func TestDecodeLenientDateTime(t *testing.T) {
+	body := decodeBody(t, `<cwmp:DownloadResponse><Completed>false</Completed><StartTime>2020-01-02T20:50:49</StartTime><CompleteTime>0001-01-01T00:00:00</CompleteTime></cwmp:DownloadResponse>`)
+
+	r, ok := body.(*DownloadResponse)
+	if !ok {
+		t.Fatalf("Expected DownloadResponse got (%T)", body)
+	}
+
+	assertEqual(t, "2020-01-02T20:50:49Z", r.StartTime.String())
+	assertEqual(t, true, r.CompleteTime.IsUnknown())
+
+	assertEncode(t, r, `<DownloadResponse xmlns="urn:dslforum-org:cwmp-1-0"><Completed>false</Completed><StartTime>2020-01-02T20:50:49Z</StartTime><CompleteTime>0001-01-01T00:00:00Z</CompleteTime></DownloadResponse>`)
+}
+
+func TestDateTimeJSON(t *testing.T) {
+	tests := []struct {
+		in   DateTime
+		want string
+	}{
+		{DateTime{Time: time.Date(2020, 01, 02, 20, 50, 49, 0, time.FixedZone("EST", -5*60*60))}, `"2020-01-03T01:50:49Z"`},
+		{DateTime{}, `"0001-01-01T00:00:00Z"`},
+		{DateTime{Unset: true}, `""`},
+	}
+
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.in)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		assertEqual(t, tt.want, string(b))
+
+		var got DateTime
+
+		err = json.Unmarshal(b, &got)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		assertEqual(t, tt.in.String(), got.String())
+		assertEqual(t, tt.in.Unset, got.Unset)
+	}
+
+	var d DateTime
+
+	err := json.Unmarshal([]byte(`"2020-01-02T20:50:49+0100"`), &d)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertEqual(t, "2020-01-02T19:50:49Z", d.String())
+}
+
+func TestDateTimeUnsetRoundTrip(t *testing.T) {
+	body := decodeBody(t, `<cwmp:DownloadResponse><Completed>false</Completed><StartTime></StartTime><CompleteTime>0001-01-01T00:00:00Z</CompleteTime></cwmp:DownloadResponse>`)
+
+	r, ok := body.(*DownloadResponse)
+	if !ok {
+		t.Fatalf("Expected DownloadResponse got (%T)", body)
+	}
+
+	assertEqual(t, true, r.StartTime.Unset)
+
+	assertEncode(t, r, `<DownloadResponse xmlns="urn:dslforum-org:cwmp-1-0"><Completed>false</Completed><StartTime></StartTime><CompleteTime>0001-01-01T00:00:00Z</CompleteTime></DownloadResponse>`)
+}
@@ -0,0 +1,185 @@
+package cwmp
+
+import (
+	"fmt"
+	"strings"
+)
+
+type EventCode string
+
+const (
+	EventBootstrap                       EventCode = "0 BOOTSTRAP"
+	EventBoot                            EventCode = "1 BOOT"
+	EventPeriodic                        EventCode = "2 PERIODIC"
+	EventScheduled                       EventCode = "3 SCHEDULED"
+	EventValueChange                     EventCode = "4 VALUE CHANGE"
+	EventKicked                          EventCode = "5 KICKED"
+	EventConnectionRequest               EventCode = "6 CONNECTION REQUEST"
+	EventTransferComplete                EventCode = "7 TRANSFER COMPLETE"
+	EventDiagnosticsComplete             EventCode = "8 DIAGNOSTICS COMPLETE"
+	EventRequestDownload                 EventCode = "9 REQUEST DOWNLOAD"
+	EventAutonomousTransferComplete      EventCode = "10 AUTONOMOUS TRANSFER COMPLETE"
+	EventDUStateChangeComplete           EventCode = "11 DU STATE CHANGE COMPLETE"
+	EventAutonomousDUStateChangeComplete EventCode = "12 AUTONOMOUS DU STATE CHANGE COMPLETE"
+	EventWakeup                          EventCode = "13 WAKEUP"
+	EventHeartbeat                       EventCode = "14 HEARTBEAT"
+
+	EventMReboot           EventCode = "M Reboot"
+	EventMScheduleInform   EventCode = "M ScheduleInform"
+	EventMDownload         EventCode = "M Download"
+	EventMScheduleDownload EventCode = "M ScheduleDownload"
+	EventMUpload           EventCode = "M Upload"
+	EventMChangeDUState    EventCode = "M ChangeDUState"
+)
+
+// EventInfo is a parsed event code together with how the CPE treats it.
+type EventInfo struct {
+	Code EventCode
+
+	// Method is the ACS method of an "M <method>" event.
+	Method string
+
+	// OUI and Name identify a vendor "X <OUI> <event>" event.
+	OUI  string
+	Name string
+
+	// Multiple is set for events that may appear more than once in an
+	// Inform, each with its own CommandKey.
+	Multiple bool
+
+	// Retry is set for events the CPE keeps and delivers again if the
+	// session fails. Other events are discarded.
+	Retry bool
+}
+
+func (i EventInfo) IsMethod() bool {
+	return i.Method != ""
+}
+
+func (i EventInfo) IsVendor() bool {
+	return i.OUI != ""
+}
+
+type eventBehavior struct {
+	Multiple bool
+	Retry    bool
+}
+
+var eventBehaviors = map[EventCode]eventBehavior{
+	EventBootstrap:                       {false, true},
+	EventBoot:                            {false, true},
+	EventPeriodic:                        {false, true},
+	EventScheduled:                       {false, true},
+	EventValueChange:                     {false, true},
+	EventKicked:                          {false, false},
+	EventConnectionRequest:               {false, false},
+	EventTransferComplete:                {false, true},
+	EventDiagnosticsComplete:             {false, true},
+	EventRequestDownload:                 {false, false},
+	EventAutonomousTransferComplete:      {false, true},
+	EventDUStateChangeComplete:           {false, true},
+	EventAutonomousDUStateChangeComplete: {false, true},
+	EventWakeup:                          {false, true},
+	EventHeartbeat:                       {false, false},
+
+	EventMReboot:           {false, true},
+	EventMScheduleInform:   {true, true},
+	EventMDownload:         {true, true},
+	EventMScheduleDownload: {true, true},
+	EventMUpload:           {true, true},
+	EventMChangeDUState:    {true, true},
+}
+
+// ParseEventCode parses a standard, M method or X vendor event code. M events
+// for methods the package doesn't know and all vendor events are accepted and
+// reported as single events that aren't retried.
+func ParseEventCode(s string) (EventInfo, error) {
+	code := EventCode(strings.TrimSpace(s))
+
+	info := EventInfo{Code: code}
+
+	b, known := eventBehaviors[code]
+	info.Multiple = b.Multiple
+	info.Retry = b.Retry
+
+	f := strings.Fields(string(code))
+
+	switch {
+	case len(f) == 2 && f[0] == "M":
+		info.Method = f[1]
+	case len(f) >= 3 && f[0] == "X":
+		info.OUI = f[1]
+		info.Name = strings.Join(f[2:], " ")
+	case !known:
+		return EventInfo{}, fmt.Errorf("cwmp: Unknown event code (%s)", s)
+	}
+
+	return info, nil
+}
+
+// Parse parses the event's code.
+func (e Event) Parse() (EventInfo, error) {
+	return ParseEventCode(string(e.EventCode))
+}
+
+// Completion is an ACS method whose completion an Inform reports with an
+// "M <method>" event.
+type Completion struct {
+	Method     string
+	CommandKey string
+}
+
+// HasEvent reports whether the Inform carries the event code.
+func (i *Inform) HasEvent(code EventCode) bool {
+	for _, e := range i.Event {
+		if EventCode(strings.TrimSpace(string(e.EventCode))) == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CommandKeys returns the CommandKeys of every occurrence of the event code.
+func (i *Inform) CommandKeys(code EventCode) []string {
+	var keys []string
+
+	for _, e := range i.Event {
+		if EventCode(strings.TrimSpace(string(e.EventCode))) == code {
+			keys = append(keys, e.CommandKey)
+		}
+	}
+
+	return keys
+}
+
+// Completions lists the methods the Inform reports as complete.
+func (i *Inform) Completions() []Completion {
+	var c []Completion
+
+	for _, e := range i.Event {
+		info, err := e.Parse()
+		if err != nil || !info.IsMethod() {
+			continue
+		}
+
+		c = append(c, Completion{
+			Method:     info.Method,
+			CommandKey: e.CommandKey,
+		})
+	}
+
+	return c
+}
+
+// HasCompletion reports whether the Inform reports method as complete for
+// commandKey.
+func (i *Inform) HasCompletion(method, commandKey string) bool {
+	for _, c := range i.Completions() {
+		if c.Method == method && c.CommandKey == commandKey {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,77 @@
+package cwmp
+
+import (
+	"testing"
+)
+
+func TestParseEventCode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want EventInfo
+	}{
+		{"0 BOOTSTRAP", EventInfo{Code: EventBootstrap, Retry: true}},
+		{" 1 BOOT ", EventInfo{Code: EventBoot, Retry: true}},
+		{"4 VALUE CHANGE", EventInfo{Code: EventValueChange, Retry: true}},
+		{"6 CONNECTION REQUEST", EventInfo{Code: EventConnectionRequest}},
+		{"14 HEARTBEAT", EventInfo{Code: EventHeartbeat}},
+		{"M Reboot", EventInfo{Code: EventMReboot, Method: "Reboot", Retry: true}},
+		{"M Download", EventInfo{Code: EventMDownload, Method: "Download", Multiple: true, Retry: true}},
+		{"M X_00D09E_Reset", EventInfo{Code: "M X_00D09E_Reset", Method: "X_00D09E_Reset"}},
+		{"X 00D09E FIRMWARE READY", EventInfo{Code: "X 00D09E FIRMWARE READY", OUI: "00D09E", Name: "FIRMWARE READY"}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseEventCode(tt.in)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		assertEqual(t, tt.want, got)
+	}
+
+	for _, in := range []string{"", "15 UNKNOWN", "M", "X 00D09E", "BOOT"} {
+		_, err := ParseEventCode(in)
+		if err == nil {
+			t.Errorf("Expected an error for (%s)", in)
+		}
+	}
+}
+
+func TestEventInfo(t *testing.T) {
+	info, err := Event{EventCode: EventMScheduleInform, CommandKey: "wake"}.Parse()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertEqual(t, true, info.IsMethod())
+	assertEqual(t, false, info.IsVendor())
+	assertEqual(t, "ScheduleInform", info.Method)
+}
+
+func TestInformEvents(t *testing.T) {
+	inform := &Inform{
+		Event: []Event{
+			Event{EventCode: EventBoot},
+			Event{EventCode: EventMReboot, CommandKey: "reboot-1"},
+			Event{EventCode: EventMDownload, CommandKey: "fw-6.47"},
+			Event{EventCode: EventMDownload, CommandKey: "cfg-2"},
+			Event{EventCode: "X 00D09E FIRMWARE READY"},
+		},
+	}
+
+	assertEqual(t, true, inform.HasEvent(EventBoot))
+	assertEqual(t, false, inform.HasEvent(EventBootstrap))
+	assertEqual(t, true, inform.HasEvent("X 00D09E FIRMWARE READY"))
+
+	assertEqual(t, []string{"fw-6.47", "cfg-2"}, inform.CommandKeys(EventMDownload))
+	assertEqual(t, []string(nil), inform.CommandKeys(EventMUpload))
+
+	assertEqual(t, []Completion{
+		Completion{Method: "Reboot", CommandKey: "reboot-1"},
+		Completion{Method: "Download", CommandKey: "fw-6.47"},
+		Completion{Method: "Download", CommandKey: "cfg-2"},
+	}, inform.Completions())
+
+	assertEqual(t, true, inform.HasCompletion("Download", "cfg-2"))
+	assertEqual(t, false, inform.HasCompletion("Download", "cfg-3"))
+}
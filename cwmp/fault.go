@@ -0,0 +1,147 @@
+package cwmp
+
+import (
+	"fmt"
+
+	"github.com/scottlangendyk/go-cwmp/soap"
+)
+
+type faultInfo struct {
+	String string
+	Client bool
+}
+
+var faults = map[uint]faultInfo{
+	ACSMethodNotSupported: {"Method not supported", false},
+	ACSRequestDenied:      {"Request denied (no reason specified)", false},
+	ACSInternalError:      {"Internal error", false},
+	ACSInvalidArguments:   {"Invalid arguments", true},
+	ACSResourcesExceeded:  {"Resources exceeded", false},
+	ACSRetryRequest:       {"Retry request", false},
+	ACSIncompatible:       {"ACS version incompatible with CPE", false},
+
+	CPEMethodNotSupported:          {"Method not supported", false},
+	CPERequestDenied:               {"Request denied (no reason specified)", false},
+	CPEInternalError:               {"Internal error", false},
+	CPEInvalidArguments:            {"Invalid arguments", true},
+	CPEResourcesExceeded:           {"Resources exceeded", false},
+	CPEInvalidParameterName:        {"Invalid parameter name", true},
+	CPEInvalidParameterType:        {"Invalid parameter type", true},
+	CPEInvalidParameterValue:       {"Invalid parameter value", true},
+	CPEParameterNotWritable:        {"Attempt to set a non-writable parameter", true},
+	CPENotificationRequestRejected: {"Notification request rejected", false},
+	CPEFileTransferFailure:         {"File transfer failure", false},
+	CPEUploadFailure:               {"Upload failure", false},
+	CPEFileTransferAuthFailure:     {"File transfer server authentication failure", false},
+	CPEUnsupportedProtocol:         {"Unsupported protocol for file transfer", false},
+	CPEMulticastJoinFailure:        {"File transfer failure: unable to join multicast group", false},
+	CPEFileServerUnreachable:       {"File transfer failure: unable to contact file server", false},
+	CPEFileAccessFailure:           {"File transfer failure: unable to access file", false},
+	CPEDownloadIncomplete:          {"File transfer failure: unable to complete download", false},
+	CPEFileCorrupted:               {"File transfer failure: file corrupted or otherwise unusable", false},
+	CPEFileAuthFailure:             {"File transfer failure: file authentication failure", false},
+	CPETimeWindowsExceeded:         {"File transfer failure: unable to complete download within specified time windows", false},
+	CPECancelNotPermitted:          {"Cancelation of file transfer not permitted in current transfer state", true},
+	CPEInvalidUUID:                 {"Invalid UUID format", false},
+	CPEUnknownExecutionEnv:         {"Unknown execution environment", false},
+	CPEDisabledExecutionEnv:        {"Disabled execution environment", false},
+	CPEExecutionEnvMismatch:        {"Deployment unit to execution environment mismatch", false},
+	CPEDuplicateDeploymentUnit:     {"Duplicate deployment unit", false},
+	CPESystemResourcesExceeded:     {"System resources exceeded", false},
+	CPEUnknownDeploymentUnit:       {"Unknown deployment unit", false},
+	CPEInvalidDeploymentUnitState:  {"Invalid deployment unit state", false},
+	CPEDowngradeNotPermitted:       {"Invalid deployment unit update: downgrade not permitted", false},
+	CPEVersionNotSpecified:         {"Invalid deployment unit update: version not specified", false},
+	CPEVersionExists:               {"Invalid deployment unit update: version already exists", false},
+}
+
+// FaultString returns the standard fault string for code, or an empty string
+// for vendor and unknown codes.
+func FaultString(code uint) string {
+	return faults[code].String
+}
+
+// SOAPFaultCode returns the SOAP faultcode, Client or Server, that goes with
+// the CWMP fault code. Vendor and unknown codes are Server faults.
+func SOAPFaultCode(code uint) string {
+	if faults[code].Client {
+		return "Client"
+	}
+
+	return "Server"
+}
+
+// NewFault returns a fault with code and its standard fault string.
+func NewFault(code uint) *Fault {
+	return &Fault{
+		Code:   code,
+		String: FaultString(code),
+	}
+}
+
+// AddParameterFault records a SetParameterValues fault for the parameter name.
+func (f *Fault) AddParameterFault(name string, code uint) {
+	f.SetParameterValuesFault = append(f.SetParameterValuesFault, SetParameterValuesFault{
+		Code:   code,
+		String: FaultString(code),
+		Name:   name,
+	})
+}
+
+// ParameterFault returns the SetParameterValues fault for the parameter name.
+func (f *Fault) ParameterFault(name string) (*SetParameterValuesFault, bool) {
+	for i := range f.SetParameterValuesFault {
+		if f.SetParameterValuesFault[i].Name == name {
+			return &f.SetParameterValuesFault[i], true
+		}
+	}
+
+	return nil, false
+}
+
+func (f *Fault) Error() string {
+	s := f.String
+	if s == "" {
+		s = FaultString(f.Code)
+	}
+
+	if len(f.SetParameterValuesFault) > 0 {
+		return fmt.Sprintf("cwmp: Fault %d %s (%d parameters)", f.Code, s, len(f.SetParameterValuesFault))
+	}
+
+	return fmt.Sprintf("cwmp: Fault %d %s", f.Code, s)
+}
+
+// Is reports whether target is a fault with the same code, so that
+// errors.Is(err, NewFault(CPEInvalidParameterName)) matches any such fault.
+func (f *Fault) Is(target error) bool {
+	t, ok := target.(*Fault)
+	if !ok {
+		return false
+	}
+
+	return t.Code == f.Code
+}
+
+// SOAPFault wraps f in a SOAP fault with the matching faultcode.
+func (f *Fault) SOAPFault() *soap.Fault {
+	return &soap.Fault{
+		Code:   SOAPFaultCode(f.Code),
+		String: "CWMP fault",
+		Detail: f,
+	}
+}
+
+// FromSOAPFault returns the CWMP fault carried in the detail of f.
+func FromSOAPFault(f *soap.Fault) (*Fault, bool) {
+	if f == nil {
+		return nil, false
+	}
+
+	cf, ok := f.Detail.(*Fault)
+	if !ok {
+		return nil, false
+	}
+
+	return cf, true
+}
@@ -0,0 +1,120 @@
+package cwmp
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/scottlangendyk/go-cwmp/soap"
+)
+
+func TestFaultCatalog(t *testing.T) {
+	for code := uint(9000); code <= 9032; code++ {
+		if FaultString(code) == "" {
+			t.Errorf("Missing fault string for (%d)", code)
+		}
+	}
+
+	for code := uint(8000); code <= 8006; code++ {
+		if FaultString(code) == "" {
+			t.Errorf("Missing fault string for (%d)", code)
+		}
+	}
+
+	assertEqual(t, "File transfer server authentication failure", FaultString(CPEFileTransferAuthFailure))
+	assertEqual(t, "", FaultString(9800))
+
+	assertEqual(t, "Server", SOAPFaultCode(9800))
+}
+
+func TestSOAPFaultCode(t *testing.T) {
+	client := map[uint]bool{
+		ACSInvalidArguments:      true,
+		CPEInvalidArguments:      true,
+		CPEInvalidParameterName:  true,
+		CPEInvalidParameterType:  true,
+		CPEInvalidParameterValue: true,
+		CPEParameterNotWritable:  true,
+		CPECancelNotPermitted:    true,
+	}
+
+	for code := range faults {
+		want := "Server"
+		if client[code] {
+			want = "Client"
+		}
+
+		if got := SOAPFaultCode(code); got != want {
+			t.Errorf("Expected (%s) for (%d) got (%s)", want, code, got)
+		}
+	}
+}
+
+func TestFaultError(t *testing.T) {
+	var err error = NewFault(CPEUnsupportedProtocol)
+
+	assertEqual(t, "cwmp: Fault 9013 Unsupported protocol for file transfer", err.Error())
+
+	wrapped := fmt.Errorf("download: %w", err)
+
+	if !errors.Is(wrapped, NewFault(CPEUnsupportedProtocol)) {
+		t.Fatal("Expected errors.Is to match the fault code")
+	}
+
+	if errors.Is(wrapped, NewFault(CPEFileTransferFailure)) {
+		t.Fatal("Expected errors.Is not to match another fault code")
+	}
+
+	var f *Fault
+	if !errors.As(wrapped, &f) {
+		t.Fatal("Expected errors.As to find the fault")
+	}
+
+	assertEqual(t, uint(CPEUnsupportedProtocol), f.Code)
+
+	assertEqual(t, "cwmp: Fault 9800 Vendor", (&Fault{Code: 9800, String: "Vendor"}).Error())
+}
+
+func TestFaultParameters(t *testing.T) {
+	f := NewFault(CPEInvalidArguments)
+	f.AddParameterFault("Device.DeviceInfo.ProvisioningCode", CPEInvalidParameterValue)
+	f.AddParameterFault("Device.ManagementServer.URL", CPEParameterNotWritable)
+
+	assertEqual(t, "cwmp: Fault 9003 Invalid arguments (2 parameters)", f.Error())
+
+	p, ok := f.ParameterFault("Device.ManagementServer.URL")
+	if !ok {
+		t.Fatal("Expected a fault for Device.ManagementServer.URL")
+	}
+
+	assertEqual(t, uint(CPEParameterNotWritable), p.Code)
+	assertEqual(t, "Attempt to set a non-writable parameter", p.String)
+
+	_, ok = f.ParameterFault("Device.DeviceInfo.SerialNumber")
+	if ok {
+		t.Fatal("Expected no fault for Device.DeviceInfo.SerialNumber")
+	}
+}
+
+func TestSOAPFault(t *testing.T) {
+	f := NewFault(CPEInvalidParameterName)
+
+	sf := f.SOAPFault()
+	assertEqual(t, "Client", sf.Code)
+	assertEqual(t, "CWMP fault", sf.String)
+
+	got, ok := FromSOAPFault(sf)
+	if !ok || got != f {
+		t.Fatalf("Expected (%v) got (%v)", f, got)
+	}
+
+	_, ok = FromSOAPFault(&soap.Fault{Detail: "oops"})
+	if ok {
+		t.Fatal("Expected no CWMP fault")
+	}
+
+	_, ok = FromSOAPFault(nil)
+	if ok {
+		t.Fatal("Expected no CWMP fault")
+	}
+}
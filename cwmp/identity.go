@@ -0,0 +1,149 @@
+package cwmp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Identity identifies a device by the OUI, ProductClass and SerialNumber from
+// its DeviceId. It is comparable and can be used as a map key.
+type Identity struct {
+	OUI          string
+	ProductClass string
+	SerialNumber string
+}
+
+func (id DeviceID) Identity() Identity {
+	return Identity{
+		OUI:          id.OUI,
+		ProductClass: id.ProductClass,
+		SerialNumber: id.SerialNumber,
+	}
+}
+
+func (i *Inform) Identity() Identity {
+	return i.DeviceID.Identity()
+}
+
+func shouldEscape(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return false
+	case c == '_' || c == '.' || c == '~':
+		return false
+	}
+
+	return true
+}
+
+func escapeIdentity(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if shouldEscape(s[i]) {
+			fmt.Fprintf(&b, "%%%02X", s[i])
+			continue
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+
+	return 0, false
+}
+
+func unescapeIdentity(s string) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		if i+2 >= len(s) {
+			return "", fmt.Errorf("cwmp: Invalid escape in identity (%s)", s)
+		}
+
+		hi, ok1 := unhex(s[i+1])
+		lo, ok2 := unhex(s[i+2])
+		if !ok1 || !ok2 {
+			return "", fmt.Errorf("cwmp: Invalid escape in identity (%s)", s)
+		}
+
+		b.WriteByte(hi<<4 | lo)
+		i += 2
+	}
+
+	return b.String(), nil
+}
+
+// String formats the identity as OUI-ProductClass-SerialNumber, or
+// OUI-SerialNumber if there is no ProductClass. Every character other than
+// letters, digits, "_", "." and "~" is percent-escaped, including "-".
+func (id Identity) String() string {
+	if id.ProductClass == "" {
+		return escapeIdentity(id.OUI) + "-" + escapeIdentity(id.SerialNumber)
+	}
+
+	return escapeIdentity(id.OUI) + "-" + escapeIdentity(id.ProductClass) + "-" + escapeIdentity(id.SerialNumber)
+}
+
+// ParseIdentity parses an identity formatted by Identity.String.
+func ParseIdentity(s string) (Identity, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 2 && len(parts) != 3 {
+		return Identity{}, fmt.Errorf("cwmp: Invalid identity (%s)", s)
+	}
+
+	for i, p := range parts {
+		u, err := unescapeIdentity(p)
+		if err != nil {
+			return Identity{}, err
+		}
+
+		parts[i] = u
+	}
+
+	id := Identity{
+		OUI:          parts[0],
+		SerialNumber: parts[len(parts)-1],
+	}
+
+	if len(parts) == 3 {
+		id.ProductClass = parts[1]
+	}
+
+	if id.OUI == "" || id.SerialNumber == "" {
+		return Identity{}, fmt.Errorf("cwmp: Invalid identity (%s)", s)
+	}
+
+	return id, nil
+}
+
+func (id Identity) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *Identity) UnmarshalText(b []byte) error {
+	v, err := ParseIdentity(string(b))
+	if err != nil {
+		return err
+	}
+
+	*id = v
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package cwmp
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestIdentityString(t *testing.T) {
+	tests := []struct {
+		id   Identity
+		want string
+	}{
+		{Identity{OUI: "E48D8C", ProductClass: "hAP mini", SerialNumber: "B7B20A1DE3F0"}, "E48D8C-hAP%20mini-B7B20A1DE3F0"},
+		{Identity{OUI: "00D09E", SerialNumber: "SN-42/a"}, "00D09E-SN%2D42%2Fa"},
+		{Identity{OUI: "00D09E", ProductClass: "IGD_v1.2~x", SerialNumber: "1%"}, "00D09E-IGD_v1.2~x-1%25"},
+	}
+
+	for _, tt := range tests {
+		assertEqual(t, tt.want, tt.id.String())
+
+		got, err := ParseIdentity(tt.want)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		assertEqual(t, tt.id, got)
+	}
+}
+
+func TestParseIdentityInvalid(t *testing.T) {
+	for _, s := range []string{"", "E48D8C", "a-b-c-d", "-1", "E48D8C-", "E48D8C-%2", "E48D8C-%zz"} {
+		_, err := ParseIdentity(s)
+		if err == nil {
+			t.Errorf("Expected an error for (%s)", s)
+		}
+	}
+
+	id, err := ParseIdentity("e48d8c-%3a")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertEqual(t, ":", id.SerialNumber)
+}
+
+func TestIdentityJSON(t *testing.T) {
+	devices := map[Identity]string{
+		Identity{OUI: "E48D8C", ProductClass: "hAP mini", SerialNumber: "1"}: "kitchen",
+	}
+
+	b, err := json.Marshal(devices)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertEqual(t, `{"E48D8C-hAP%20mini-1":"kitchen"}`, string(b))
+
+	var got map[Identity]string
+
+	err = json.Unmarshal(b, &got)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertEqual(t, devices, got)
+
+	var id Identity
+
+	err = json.Unmarshal([]byte(`"nope"`), &id)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+}
+
+func TestInformIdentity(t *testing.T) {
+	inform := decodeInformFile(t, "testdata/inform.xml")
+
+	assertEqual(t, Identity{OUI: "E48D8C", ProductClass: "hAP mini", SerialNumber: "B7B20A1DE3F0"}, inform.Identity())
+	assertEqual(t, "E48D8C-hAP%20mini-B7B20A1DE3F0", inform.Identity().String())
+}
@@ -0,0 +1,401 @@
+package cwmp
+
+func (Inform) Method() *Method {
+	return mustLookup("Inform")
+}
+
+func (Inform) IsResponse() bool {
+	return false
+}
+
+func (InformResponse) Method() *Method {
+	return mustLookup("Inform")
+}
+
+func (InformResponse) IsResponse() bool {
+	return true
+}
+
+func (GetRPCMethods) Method() *Method {
+	return mustLookup("GetRPCMethods")
+}
+
+func (GetRPCMethods) IsResponse() bool {
+	return false
+}
+
+func (GetRPCMethodsResponse) Method() *Method {
+	return mustLookup("GetRPCMethods")
+}
+
+func (GetRPCMethodsResponse) IsResponse() bool {
+	return true
+}
+
+func (Reboot) Method() *Method {
+	return mustLookup("Reboot")
+}
+
+func (Reboot) IsResponse() bool {
+	return false
+}
+
+func (RebootResponse) Method() *Method {
+	return mustLookup("Reboot")
+}
+
+func (RebootResponse) IsResponse() bool {
+	return true
+}
+
+func (FactoryReset) Method() *Method {
+	return mustLookup("FactoryReset")
+}
+
+func (FactoryReset) IsResponse() bool {
+	return false
+}
+
+func (FactoryResetResponse) Method() *Method {
+	return mustLookup("FactoryReset")
+}
+
+func (FactoryResetResponse) IsResponse() bool {
+	return true
+}
+
+func (ScheduleInform) Method() *Method {
+	return mustLookup("ScheduleInform")
+}
+
+func (ScheduleInform) IsResponse() bool {
+	return false
+}
+
+func (ScheduleInformResponse) Method() *Method {
+	return mustLookup("ScheduleInform")
+}
+
+func (ScheduleInformResponse) IsResponse() bool {
+	return true
+}
+
+func (TransferComplete) Method() *Method {
+	return mustLookup("TransferComplete")
+}
+
+func (TransferComplete) IsResponse() bool {
+	return false
+}
+
+func (TransferCompleteResponse) Method() *Method {
+	return mustLookup("TransferComplete")
+}
+
+func (TransferCompleteResponse) IsResponse() bool {
+	return true
+}
+
+func (AutonomousTransferComplete) Method() *Method {
+	return mustLookup("AutonomousTransferComplete")
+}
+
+func (AutonomousTransferComplete) IsResponse() bool {
+	return false
+}
+
+func (AutonomousTransferCompleteResponse) Method() *Method {
+	return mustLookup("AutonomousTransferComplete")
+}
+
+func (AutonomousTransferCompleteResponse) IsResponse() bool {
+	return true
+}
+
+func (ChangeDUState) Method() *Method {
+	return mustLookup("ChangeDUState")
+}
+
+func (ChangeDUState) IsResponse() bool {
+	return false
+}
+
+func (ChangeDUStateResponse) Method() *Method {
+	return mustLookup("ChangeDUState")
+}
+
+func (ChangeDUStateResponse) IsResponse() bool {
+	return true
+}
+
+func (DUStateChangeComplete) Method() *Method {
+	return mustLookup("DUStateChangeComplete")
+}
+
+func (DUStateChangeComplete) IsResponse() bool {
+	return false
+}
+
+func (DUStateChangeCompleteResponse) Method() *Method {
+	return mustLookup("DUStateChangeComplete")
+}
+
+func (DUStateChangeCompleteResponse) IsResponse() bool {
+	return true
+}
+
+func (AutonomousDUStateChangeComplete) Method() *Method {
+	return mustLookup("AutonomousDUStateChangeComplete")
+}
+
+func (AutonomousDUStateChangeComplete) IsResponse() bool {
+	return false
+}
+
+func (AutonomousDUStateChangeCompleteResponse) Method() *Method {
+	return mustLookup("AutonomousDUStateChangeComplete")
+}
+
+func (AutonomousDUStateChangeCompleteResponse) IsResponse() bool {
+	return true
+}
+
+func (Download) Method() *Method {
+	return mustLookup("Download")
+}
+
+func (Download) IsResponse() bool {
+	return false
+}
+
+func (DownloadResponse) Method() *Method {
+	return mustLookup("Download")
+}
+
+func (DownloadResponse) IsResponse() bool {
+	return true
+}
+
+func (ScheduleDownload) Method() *Method {
+	return mustLookup("ScheduleDownload")
+}
+
+func (ScheduleDownload) IsResponse() bool {
+	return false
+}
+
+func (ScheduleDownloadResponse) Method() *Method {
+	return mustLookup("ScheduleDownload")
+}
+
+func (ScheduleDownloadResponse) IsResponse() bool {
+	return true
+}
+
+func (CancelTransfer) Method() *Method {
+	return mustLookup("CancelTransfer")
+}
+
+func (CancelTransfer) IsResponse() bool {
+	return false
+}
+
+func (CancelTransferResponse) Method() *Method {
+	return mustLookup("CancelTransfer")
+}
+
+func (CancelTransferResponse) IsResponse() bool {
+	return true
+}
+
+func (GetQueuedTransfers) Method() *Method {
+	return mustLookup("GetQueuedTransfers")
+}
+
+func (GetQueuedTransfers) IsResponse() bool {
+	return false
+}
+
+func (GetQueuedTransfersResponse) Method() *Method {
+	return mustLookup("GetQueuedTransfers")
+}
+
+func (GetQueuedTransfersResponse) IsResponse() bool {
+	return true
+}
+
+func (GetAllQueuedTransfers) Method() *Method {
+	return mustLookup("GetAllQueuedTransfers")
+}
+
+func (GetAllQueuedTransfers) IsResponse() bool {
+	return false
+}
+
+func (GetAllQueuedTransfersResponse) Method() *Method {
+	return mustLookup("GetAllQueuedTransfers")
+}
+
+func (GetAllQueuedTransfersResponse) IsResponse() bool {
+	return true
+}
+
+func (RequestDownload) Method() *Method {
+	return mustLookup("RequestDownload")
+}
+
+func (RequestDownload) IsResponse() bool {
+	return false
+}
+
+func (RequestDownloadResponse) Method() *Method {
+	return mustLookup("RequestDownload")
+}
+
+func (RequestDownloadResponse) IsResponse() bool {
+	return true
+}
+
+func (Kicked) Method() *Method {
+	return mustLookup("Kicked")
+}
+
+func (Kicked) IsResponse() bool {
+	return false
+}
+
+func (KickedResponse) Method() *Method {
+	return mustLookup("Kicked")
+}
+
+func (KickedResponse) IsResponse() bool {
+	return true
+}
+
+func (GetParameterValues) Method() *Method {
+	return mustLookup("GetParameterValues")
+}
+
+func (GetParameterValues) IsResponse() bool {
+	return false
+}
+
+func (GetParameterValuesResponse) Method() *Method {
+	return mustLookup("GetParameterValues")
+}
+
+func (GetParameterValuesResponse) IsResponse() bool {
+	return true
+}
+
+func (SetParameterValues) Method() *Method {
+	return mustLookup("SetParameterValues")
+}
+
+func (SetParameterValues) IsResponse() bool {
+	return false
+}
+
+func (SetParameterValuesResponse) Method() *Method {
+	return mustLookup("SetParameterValues")
+}
+
+func (SetParameterValuesResponse) IsResponse() bool {
+	return true
+}
+
+func (GetParameterNames) Method() *Method {
+	return mustLookup("GetParameterNames")
+}
+
+func (GetParameterNames) IsResponse() bool {
+	return false
+}
+
+func (GetParameterNamesResponse) Method() *Method {
+	return mustLookup("GetParameterNames")
+}
+
+func (GetParameterNamesResponse) IsResponse() bool {
+	return true
+}
+
+func (AddObject) Method() *Method {
+	return mustLookup("AddObject")
+}
+
+func (AddObject) IsResponse() bool {
+	return false
+}
+
+func (AddObjectResponse) Method() *Method {
+	return mustLookup("AddObject")
+}
+
+func (AddObjectResponse) IsResponse() bool {
+	return true
+}
+
+func (DeleteObject) Method() *Method {
+	return mustLookup("DeleteObject")
+}
+
+func (DeleteObject) IsResponse() bool {
+	return false
+}
+
+func (DeleteObjectResponse) Method() *Method {
+	return mustLookup("DeleteObject")
+}
+
+func (DeleteObjectResponse) IsResponse() bool {
+	return true
+}
+
+func (GetParameterAttributes) Method() *Method {
+	return mustLookup("GetParameterAttributes")
+}
+
+func (GetParameterAttributes) IsResponse() bool {
+	return false
+}
+
+func (GetParameterAttributesResponse) Method() *Method {
+	return mustLookup("GetParameterAttributes")
+}
+
+func (GetParameterAttributesResponse) IsResponse() bool {
+	return true
+}
+
+func (SetParameterAttributes) Method() *Method {
+	return mustLookup("SetParameterAttributes")
+}
+
+func (SetParameterAttributes) IsResponse() bool {
+	return false
+}
+
+func (SetParameterAttributesResponse) Method() *Method {
+	return mustLookup("SetParameterAttributes")
+}
+
+func (SetParameterAttributesResponse) IsResponse() bool {
+	return true
+}
+
+func (Upload) Method() *Method {
+	return mustLookup("Upload")
+}
+
+func (Upload) IsResponse() bool {
+	return false
+}
+
+func (UploadResponse) Method() *Method {
+	return mustLookup("Upload")
+}
+
+func (UploadResponse) IsResponse() bool {
+	return true
+}
@@ -0,0 +1,341 @@
+package cwmp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/scottlangendyk/go-cwmp/soap"
+)
+
+// Endpoint identifies the side of a session that sends a message.
+type Endpoint int
+
+const (
+	CPE Endpoint = 1 << iota
+	ACS
+)
+
+func (e Endpoint) String() string {
+	switch e {
+	case CPE:
+		return "CPE"
+	case ACS:
+		return "ACS"
+	case CPE | ACS:
+		return "CPE|ACS"
+	}
+
+	return fmt.Sprintf("Endpoint(%d)", int(e))
+}
+
+// Peer returns the endpoint that receives what e sends.
+func (e Endpoint) Peer() Endpoint {
+	switch e {
+	case CPE:
+		return ACS
+	case ACS:
+		return CPE
+	}
+
+	return e
+}
+
+// Message is implemented by every request and response.
+type Message interface {
+	Method() *Method
+	IsResponse() bool
+}
+
+// Method describes an RPC that Decode knows about. The request is encoded in
+// an element named Name and the response in one named Name + "Response".
+// Sender is the endpoint that sends the request, some methods may be sent by
+// both.
+type Method struct {
+	Name     string
+	Sender   Endpoint
+	Request  reflect.Type
+	Response reflect.Type
+}
+
+// NewRequest returns a new, empty request for m.
+func (m *Method) NewRequest() Message {
+	return reflect.New(m.Request).Interface().(Message)
+}
+
+// NewResponse returns a new, empty response to m.
+func (m *Method) NewResponse() Message {
+	return reflect.New(m.Response).Interface().(Message)
+}
+
+// Sender returns the endpoint that sends msg.
+func Sender(msg Message) Endpoint {
+	m := msg.Method()
+	if m == nil {
+		return 0
+	}
+
+	if msg.IsResponse() {
+		return m.Sender.Peer()
+	}
+
+	return m.Sender
+}
+
+// MatchResponse checks that resp answers the request req, which it does if
+// it is the response type of the same method or a CWMP fault.
+func MatchResponse(req Message, resp interface{}) error {
+	if req.IsResponse() {
+		return fmt.Errorf("cwmp: %s is not a request", req.Method().Name)
+	}
+
+	if f, ok := resp.(*soap.Fault); ok {
+		if _, ok := f.Detail.(*Fault); ok {
+			return nil
+		}
+
+		return fmt.Errorf("cwmp: Fault without CWMP detail in response to %s", req.Method().Name)
+	}
+
+	m, ok := resp.(Message)
+	if !ok || !m.IsResponse() {
+		return fmt.Errorf("cwmp: Expected %sResponse got (%T)", req.Method().Name, resp)
+	}
+
+	if m.Method() == nil || m.Method().Name != req.Method().Name {
+		return fmt.Errorf("cwmp: Expected %sResponse got (%T)", req.Method().Name, resp)
+	}
+
+	return nil
+}
+
+var (
+	registryMu sync.RWMutex
+	methods    = make(map[string]*Method)
+	elements   = make(map[string]reflect.Type)
+)
+
+func elemType(v interface{}) reflect.Type {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// Register makes the RPC name, sent by sender, decodable as request and
+// response, which are values or pointers of the types to decode into. It is
+// meant to be called from init functions, e.g. for vendor X_<OUI>_ methods,
+// and panics if name is already registered.
+func Register(name string, sender Endpoint, request, response Message) {
+	req := elemType(request)
+	resp := elemType(response)
+
+	if name == "" || req == nil || resp == nil {
+		panic("cwmp: Register called with an empty method")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, dup := methods[name]; dup {
+		panic("cwmp: Register called twice for method " + name)
+	}
+
+	methods[name] = &Method{
+		Name:     name,
+		Sender:   sender,
+		Request:  req,
+		Response: resp,
+	}
+
+	elements[name] = req
+	elements[name+"Response"] = resp
+}
+
+// Lookup returns the registered method called name.
+func Lookup(name string) (*Method, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	m, ok := methods[name]
+
+	return m, ok
+}
+
+func mustLookup(name string) *Method {
+	m, ok := Lookup(name)
+	if !ok {
+		panic("cwmp: Method " + name + " is not registered")
+	}
+
+	return m
+}
+
+// newElement returns a pointer to a new value of the type registered for the
+// request or response element local, or nil if there isn't one.
+func newElement(local string) interface{} {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	t, ok := elements[local]
+	if !ok {
+		return nil
+	}
+
+	return reflect.New(t).Interface()
+}
+
+// Unknown holds an element of the body that no method is registered for, so
+// that it can be answered with a fault or decoded later.
+type Unknown struct {
+	XMLName xml.Name
+	Attr    []xml.Attr
+	Tokens  []xml.Token
+}
+
+func (u *Unknown) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	u.XMLName = start.Name
+	u.Attr = stripNamespaceDecls(start.Attr)
+	u.Tokens = nil
+
+	depth := 0
+
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch el := t.(type) {
+		case xml.StartElement:
+			depth++
+			el.Attr = stripNamespaceDecls(el.Attr)
+			t = el
+		case xml.EndElement:
+			if depth == 0 {
+				return nil
+			}
+
+			depth--
+		}
+
+		u.Tokens = append(u.Tokens, xml.CopyToken(t))
+	}
+}
+
+func (u Unknown) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{
+		Name: u.XMLName,
+		Attr: u.Attr,
+	}
+
+	err := e.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range u.Tokens {
+		err = e.EncodeToken(t)
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// Method returns the registered method named after the element, or one with
+// only a Name if there isn't one.
+func (u Unknown) Method() *Method {
+	name := strings.TrimSuffix(u.XMLName.Local, "Response")
+
+	m, ok := Lookup(name)
+	if !ok {
+		return &Method{Name: name}
+	}
+
+	return m
+}
+
+func (u Unknown) IsResponse() bool {
+	return strings.HasSuffix(u.XMLName.Local, "Response")
+}
+
+// Decode decodes the element into v, e.g. once a plugin knows what it is.
+func (u *Unknown) Decode(v interface{}) error {
+	start := xml.StartElement{
+		Name: u.XMLName,
+		Attr: u.Attr,
+	}
+
+	r := &tokenList{}
+	r.tokens = append(r.tokens, start)
+	r.tokens = append(r.tokens, u.Tokens...)
+	r.tokens = append(r.tokens, start.End())
+
+	return xml.NewTokenDecoder(r).Decode(v)
+}
+
+// stripNamespaceDecls drops xmlns attributes, which are redundant once names
+// have been resolved and can't be re-encoded as attributes.
+func stripNamespaceDecls(attrs []xml.Attr) []xml.Attr {
+	var out []xml.Attr
+
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+
+		out = append(out, attr)
+	}
+
+	return out
+}
+
+type tokenList struct {
+	tokens []xml.Token
+}
+
+func (l *tokenList) Token() (xml.Token, error) {
+	if len(l.tokens) == 0 {
+		return nil, io.EOF
+	}
+
+	t := l.tokens[0]
+	l.tokens = l.tokens[1:]
+
+	return t, nil
+}
+
+func init() {
+	Register("Inform", CPE, Inform{}, InformResponse{})
+	Register("GetRPCMethods", CPE|ACS, GetRPCMethods{}, GetRPCMethodsResponse{})
+	Register("Reboot", ACS, Reboot{}, RebootResponse{})
+	Register("FactoryReset", ACS, FactoryReset{}, FactoryResetResponse{})
+	Register("ScheduleInform", ACS, ScheduleInform{}, ScheduleInformResponse{})
+	Register("TransferComplete", CPE, TransferComplete{}, TransferCompleteResponse{})
+	Register("AutonomousTransferComplete", CPE, AutonomousTransferComplete{}, AutonomousTransferCompleteResponse{})
+	Register("ChangeDUState", ACS, ChangeDUState{}, ChangeDUStateResponse{})
+	Register("DUStateChangeComplete", CPE, DUStateChangeComplete{}, DUStateChangeCompleteResponse{})
+	Register("AutonomousDUStateChangeComplete", CPE, AutonomousDUStateChangeComplete{}, AutonomousDUStateChangeCompleteResponse{})
+	Register("Download", ACS, Download{}, DownloadResponse{})
+	Register("ScheduleDownload", ACS, ScheduleDownload{}, ScheduleDownloadResponse{})
+	Register("CancelTransfer", ACS, CancelTransfer{}, CancelTransferResponse{})
+	Register("GetQueuedTransfers", ACS, GetQueuedTransfers{}, GetQueuedTransfersResponse{})
+	Register("GetAllQueuedTransfers", ACS, GetAllQueuedTransfers{}, GetAllQueuedTransfersResponse{})
+	Register("RequestDownload", CPE, RequestDownload{}, RequestDownloadResponse{})
+	Register("Kicked", CPE, Kicked{}, KickedResponse{})
+	Register("GetParameterValues", ACS, GetParameterValues{}, GetParameterValuesResponse{})
+	Register("SetParameterValues", ACS, SetParameterValues{}, SetParameterValuesResponse{})
+	Register("GetParameterNames", ACS, GetParameterNames{}, GetParameterNamesResponse{})
+	Register("AddObject", ACS, AddObject{}, AddObjectResponse{})
+	Register("DeleteObject", ACS, DeleteObject{}, DeleteObjectResponse{})
+	Register("GetParameterAttributes", ACS, GetParameterAttributes{}, GetParameterAttributesResponse{})
+	Register("SetParameterAttributes", ACS, SetParameterAttributes{}, SetParameterAttributesResponse{})
+	Register("Upload", ACS, Upload{}, UploadResponse{})
+}
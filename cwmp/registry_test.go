@@ -0,0 +1,181 @@
+package cwmp
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/scottlangendyk/go-cwmp/soap"
+)
+
+type vendorReset struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 X_00D09E_Reset"`
+	Mode    string
+}
+
+type vendorResetResponse struct {
+	XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 X_00D09E_ResetResponse"`
+	Status  int
+}
+
+func decodeBody(t *testing.T, body string) interface{} {
+	input := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:cwmp="urn:dslforum-org:cwmp-1-0"><soapenv:Body>` + body + `</soapenv:Body></soapenv:Envelope>`
+
+	e, err := Decode(xml.NewDecoder(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	return e.Body
+}
+
+func (vendorReset) Method() *Method {
+	return mustLookup("X_00D09E_Reset")
+}
+
+func (vendorReset) IsResponse() bool {
+	return false
+}
+
+func (vendorResetResponse) Method() *Method {
+	return mustLookup("X_00D09E_Reset")
+}
+
+func (vendorResetResponse) IsResponse() bool {
+	return true
+}
+
+func init() {
+	Register("X_00D09E_Reset", ACS, &vendorReset{}, vendorResetResponse{})
+}
+
+func TestRegister(t *testing.T) {
+	m, ok := Lookup("X_00D09E_Reset")
+	if !ok {
+		t.Fatal("Expected X_00D09E_Reset to be registered")
+	}
+
+	assertEqual(t, "X_00D09E_Reset", m.Name)
+	assertEqual(t, ACS, m.Sender)
+	assertEqual(t, "vendorReset", m.Request.Name())
+	assertEqual(t, "vendorResetResponse", m.Response.Name())
+
+	assertRoundTrip(t, &vendorReset{Mode: "soft"}, `<X_00D09E_Reset xmlns="urn:dslforum-org:cwmp-1-0"><Mode>soft</Mode></X_00D09E_Reset>`)
+	assertRoundTrip(t, &vendorResetResponse{Status: 1}, `<X_00D09E_ResetResponse xmlns="urn:dslforum-org:cwmp-1-0"><Status>1</Status></X_00D09E_ResetResponse>`)
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected Register to panic")
+		}
+	}()
+
+	Register("Inform", CPE, Inform{}, InformResponse{})
+}
+
+func TestLookupUnregistered(t *testing.T) {
+	_, ok := Lookup("X_000000_Missing")
+	if ok {
+		t.Fatal("Expected X_000000_Missing to be unregistered")
+	}
+}
+
+func TestDecodeInformResponse(t *testing.T) {
+	body := decodeBody(t, `<cwmp:InformResponse><MaxEnvelopes>1</MaxEnvelopes></cwmp:InformResponse>`)
+
+	r, ok := body.(*InformResponse)
+	if !ok {
+		t.Fatalf("Expected InformResponse got (%T)", body)
+	}
+
+	assertEqual(t, uint(1), r.MaxEnvelopes)
+}
+
+func TestDecodeUnknown(t *testing.T) {
+	body := decodeBody(t, `<cwmp:X_000000_Foo cwmp:flag="1"><Mode xmlns:v="urn:example">hard</Mode><Extra/></cwmp:X_000000_Foo>`)
+
+	u, ok := body.(*Unknown)
+	if !ok {
+		t.Fatalf("Expected Unknown got (%T)", body)
+	}
+
+	assertEqual(t, xml.Name{Space: XMLSpace, Local: "X_000000_Foo"}, u.XMLName)
+	assertEqual(t, 1, len(u.Attr))
+
+	assertEncode(t, u, `<X_000000_Foo xmlns="urn:dslforum-org:cwmp-1-0" xmlns:_="urn:dslforum-org:cwmp-1-0" _:flag="1"><Mode>hard</Mode><Extra></Extra></X_000000_Foo>`)
+
+	var v struct {
+		XMLName xml.Name `xml:"urn:dslforum-org:cwmp-1-0 X_000000_Foo"`
+		Flag    string   `xml:"urn:dslforum-org:cwmp-1-0 flag,attr"`
+		Mode    string
+	}
+
+	err := u.Decode(&v)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertEqual(t, "1", v.Flag)
+	assertEqual(t, "hard", v.Mode)
+
+	assertEqual(t, "X_000000_Foo", u.Method().Name)
+	assertEqual(t, false, u.IsResponse())
+}
+
+func TestSender(t *testing.T) {
+	assertEqual(t, CPE, Sender(&Inform{}))
+	assertEqual(t, ACS, Sender(&InformResponse{}))
+	assertEqual(t, ACS, Sender(&Reboot{}))
+	assertEqual(t, CPE, Sender(RebootResponse{}))
+	assertEqual(t, CPE|ACS, Sender(&GetRPCMethodsResponse{}))
+	assertEqual(t, ACS, Sender(&vendorReset{}))
+	assertEqual(t, Endpoint(0), Sender(&Unknown{XMLName: xml.Name{Local: "X_000000_Foo"}}))
+
+	assertEqual(t, "CPE|ACS", (CPE | ACS).String())
+}
+
+func TestNewRequest(t *testing.T) {
+	assertEqual(t, &GetParameterValues{}, (&GetParameterValuesResponse{}).Method().NewRequest())
+	assertEqual(t, &vendorReset{}, mustLookup("X_00D09E_Reset").NewRequest())
+}
+
+func TestNewResponse(t *testing.T) {
+	assertEqual(t, &GetParameterValuesResponse{}, (&GetParameterValues{}).Method().NewResponse())
+	assertEqual(t, &vendorResetResponse{}, mustLookup("X_00D09E_Reset").NewResponse())
+}
+
+func TestMatchResponse(t *testing.T) {
+	req := &GetParameterValues{ParameterNames: StringList{"Device."}}
+
+	err := MatchResponse(req, &GetParameterValuesResponse{})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	err = MatchResponse(req, &soap.Fault{Detail: &Fault{Code: CPEInvalidParameterName}})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, resp := range []interface{}{
+		&SetParameterValuesResponse{},
+		&GetParameterValues{},
+		&soap.Fault{Detail: "oops"},
+		"GetParameterValuesResponse",
+		&Unknown{XMLName: xml.Name{Local: "X_000000_FooResponse"}},
+	} {
+		if MatchResponse(req, resp) == nil {
+			t.Errorf("Expected (%T) not to match", resp)
+		}
+	}
+
+	if MatchResponse(&RebootResponse{}, &RebootResponse{}) == nil {
+		t.Error("Expected a response not to match")
+	}
+
+	err = MatchResponse(&vendorReset{}, &Unknown{XMLName: xml.Name{Local: "X_00D09E_ResetResponse"}})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package cwmp
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	RootInternetGatewayDevice = "InternetGatewayDevice."
+	RootDevice                = "Device."
+)
+
+var externalIPAddress = regexp.MustCompile(`^InternetGatewayDevice\.WANDevice\.\d+\.WANConnectionDevice\.\d+\.WAN(IP|PPP)Connection\.\d+\.ExternalIPAddress$`)
+
+// DeviceSummary holds the forced inform parameters of a device, whichever
+// root data model it uses.
+type DeviceSummary struct {
+	// Root is RootInternetGatewayDevice or RootDevice, or empty if the
+	// parameters use neither.
+	Root string
+
+	DeviceSummary        string
+	SpecVersion          string
+	RootDataModelVersion string
+	HardwareVersion      string
+	SoftwareVersion      string
+	ProvisioningCode     string
+
+	ConnectionRequestURL        string
+	UDPConnectionRequestAddress string
+	ParameterKey                string
+
+	// ExternalIPAddress is the address of the first WAN connection that
+	// has one, only reported by InternetGatewayDevice devices.
+	ExternalIPAddress string
+
+	// Missing lists the forced inform parameters that weren't in the list.
+	// The ExternalIPAddress is listed with {i} placeholders.
+	Missing []string
+}
+
+type summaryField struct {
+	Path   string
+	Forced bool
+	Field  func(s *DeviceSummary) *string
+}
+
+var summaryFields = map[string][]summaryField{
+	RootInternetGatewayDevice: {
+		{"DeviceSummary", true, func(s *DeviceSummary) *string { return &s.DeviceSummary }},
+		{"DeviceInfo.SpecVersion", true, func(s *DeviceSummary) *string { return &s.SpecVersion }},
+		{"DeviceInfo.HardwareVersion", true, func(s *DeviceSummary) *string { return &s.HardwareVersion }},
+		{"DeviceInfo.SoftwareVersion", true, func(s *DeviceSummary) *string { return &s.SoftwareVersion }},
+		{"DeviceInfo.ProvisioningCode", true, func(s *DeviceSummary) *string { return &s.ProvisioningCode }},
+		{"ManagementServer.ConnectionRequestURL", true, func(s *DeviceSummary) *string { return &s.ConnectionRequestURL }},
+		{"ManagementServer.UDPConnectionRequestAddress", false, func(s *DeviceSummary) *string { return &s.UDPConnectionRequestAddress }},
+		{"ManagementServer.ParameterKey", true, func(s *DeviceSummary) *string { return &s.ParameterKey }},
+	},
+	RootDevice: {
+		{"RootDataModelVersion", true, func(s *DeviceSummary) *string { return &s.RootDataModelVersion }},
+		{"DeviceInfo.HardwareVersion", true, func(s *DeviceSummary) *string { return &s.HardwareVersion }},
+		{"DeviceInfo.SoftwareVersion", true, func(s *DeviceSummary) *string { return &s.SoftwareVersion }},
+		{"DeviceInfo.ProvisioningCode", true, func(s *DeviceSummary) *string { return &s.ProvisioningCode }},
+		{"ManagementServer.ConnectionRequestURL", true, func(s *DeviceSummary) *string { return &s.ConnectionRequestURL }},
+		{"ManagementServer.UDPConnectionRequestAddress", false, func(s *DeviceSummary) *string { return &s.UDPConnectionRequestAddress }},
+		{"ManagementServer.ParameterKey", true, func(s *DeviceSummary) *string { return &s.ParameterKey }},
+	},
+}
+
+// DataModelRoot returns the root object, RootInternetGatewayDevice or
+// RootDevice, that the parameters are named under.
+func DataModelRoot(params []ParameterValue) string {
+	for _, p := range params {
+		switch {
+		case strings.HasPrefix(p.Name, RootInternetGatewayDevice):
+			return RootInternetGatewayDevice
+		case strings.HasPrefix(p.Name, RootDevice):
+			return RootDevice
+		}
+	}
+
+	return ""
+}
+
+// Summarize fills a DeviceSummary from the parameters of an Inform.
+func Summarize(params []ParameterValue) DeviceSummary {
+	s := DeviceSummary{
+		Root: DataModelRoot(params),
+	}
+
+	if s.Root == "" {
+		return s
+	}
+
+	values := make(map[string]string, len(params))
+	for _, p := range params {
+		values[p.Name] = p.Value
+	}
+
+	for _, f := range summaryFields[s.Root] {
+		v, ok := values[s.Root+f.Path]
+		if !ok {
+			if f.Forced {
+				s.Missing = append(s.Missing, s.Root+f.Path)
+			}
+
+			continue
+		}
+
+		*f.Field(&s) = v
+	}
+
+	if s.Root == RootInternetGatewayDevice {
+		found := false
+
+		for _, p := range params {
+			if !externalIPAddress.MatchString(p.Name) {
+				continue
+			}
+
+			found = true
+
+			if s.ExternalIPAddress == "" {
+				s.ExternalIPAddress = p.Value
+			}
+		}
+
+		if !found {
+			s.Missing = append(s.Missing, "InternetGatewayDevice.WANDevice.{i}.WANConnectionDevice.{i}.WAN{IP,PPP}Connection.{i}.ExternalIPAddress")
+		}
+	}
+
+	return s
+}
+
+func (i *Inform) Summary() DeviceSummary {
+	return Summarize(i.ParameterList)
+}
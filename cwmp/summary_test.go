@@ -0,0 +1,80 @@
+package cwmp
+
+import (
+	"encoding/xml"
+	"os"
+	"testing"
+)
+
+func decodeInformFile(t *testing.T, filename string) *Inform {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	e, err := Decode(xml.NewDecoder(f))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	inform, ok := e.Body.(*Inform)
+	if !ok {
+		t.Fatal("Body is not type Inform")
+	}
+
+	return inform
+}
+
+func TestSummaryDevice(t *testing.T) {
+	s := decodeInformFile(t, "testdata/inform.xml").Summary()
+
+	assertEqual(t, DeviceSummary{
+		Root:                 RootDevice,
+		RootDataModelVersion: "2.11",
+		HardwareVersion:      "v1.0",
+		SoftwareVersion:      "6.46.1",
+		ConnectionRequestURL: "http://10.31.0.130:7547/e55b182787ec5d4c2d4bcaa39d234920bd35",
+	}, s)
+}
+
+func TestSummaryInternetGatewayDevice(t *testing.T) {
+	s := decodeInformFile(t, "testdata/inform.igd.xml").Summary()
+
+	assertEqual(t, DeviceSummary{
+		Root:                 RootInternetGatewayDevice,
+		DeviceSummary:        "InternetGatewayDevice:1.4[](Baseline:2, EthernetLAN:1)",
+		SpecVersion:          "1.0",
+		HardwareVersion:      "VANT-6",
+		SoftwareVersion:      "16.3.7636",
+		ProvisioningCode:     "TLF.1",
+		ConnectionRequestURL: "http://192.0.2.10:51005/",
+		ParameterKey:         "k1",
+		ExternalIPAddress:    "192.0.2.10",
+	}, s)
+}
+
+func TestSummaryMissing(t *testing.T) {
+	s := Summarize([]ParameterValue{
+		ParameterValue{Name: "InternetGatewayDevice.DeviceInfo.SoftwareVersion", Value: "1.0"},
+		ParameterValue{Name: "InternetGatewayDevice.ManagementServer.UDPConnectionRequestAddress", Value: "192.0.2.10:7547"},
+	})
+
+	assertEqual(t, "1.0", s.SoftwareVersion)
+	assertEqual(t, "192.0.2.10:7547", s.UDPConnectionRequestAddress)
+	assertEqual(t, []string{
+		"InternetGatewayDevice.DeviceSummary",
+		"InternetGatewayDevice.DeviceInfo.SpecVersion",
+		"InternetGatewayDevice.DeviceInfo.HardwareVersion",
+		"InternetGatewayDevice.DeviceInfo.ProvisioningCode",
+		"InternetGatewayDevice.ManagementServer.ConnectionRequestURL",
+		"InternetGatewayDevice.ManagementServer.ParameterKey",
+		"InternetGatewayDevice.WANDevice.{i}.WANConnectionDevice.{i}.WAN{IP,PPP}Connection.{i}.ExternalIPAddress",
+	}, s.Missing)
+
+	s = Summarize(nil)
+	assertEqual(t, "", s.Root)
+	assertEqual(t, []string(nil), s.Missing)
+
+	assertEqual(t, RootDevice, DataModelRoot([]ParameterValue{ParameterValue{Name: "Device.DeviceInfo.SoftwareVersion"}}))
+}
@@ -0,0 +1,159 @@
+package cwmp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/scottlangendyk/go-cwmp/xmlutil"
+)
+
+// ValueType is the XML Schema type of a parameter value, as carried in the
+// xsi:type attribute without its namespace prefix.
+type ValueType string
+
+const (
+	TypeString       ValueType = "string"
+	TypeInt          ValueType = "int"
+	TypeUnsignedInt  ValueType = "unsignedInt"
+	TypeLong         ValueType = "long"
+	TypeUnsignedLong ValueType = "unsignedLong"
+	TypeBoolean      ValueType = "boolean"
+	TypeDateTime     ValueType = "dateTime"
+	TypeBase64       ValueType = "base64"
+	TypeHexBinary    ValueType = "hexBinary"
+)
+
+func StringValue(name, v string) ParameterValue {
+	return ParameterValue{Name: name, Value: v, Type: TypeString}
+}
+
+func IntValue(name string, v int32) ParameterValue {
+	return ParameterValue{Name: name, Value: strconv.FormatInt(int64(v), 10), Type: TypeInt}
+}
+
+func UintValue(name string, v uint32) ParameterValue {
+	return ParameterValue{Name: name, Value: strconv.FormatUint(uint64(v), 10), Type: TypeUnsignedInt}
+}
+
+func LongValue(name string, v int64) ParameterValue {
+	return ParameterValue{Name: name, Value: strconv.FormatInt(v, 10), Type: TypeLong}
+}
+
+func UnsignedLongValue(name string, v uint64) ParameterValue {
+	return ParameterValue{Name: name, Value: strconv.FormatUint(v, 10), Type: TypeUnsignedLong}
+}
+
+func BoolValue(name string, v bool) ParameterValue {
+	return ParameterValue{Name: name, Value: strconv.FormatBool(v), Type: TypeBoolean}
+}
+
+func TimeValue(name string, v time.Time) ParameterValue {
+	return ParameterValue{Name: name, Value: DateTime{Time: v}.String(), Type: TypeDateTime}
+}
+
+func Base64Value(name string, v []byte) ParameterValue {
+	return ParameterValue{Name: name, Value: base64.StdEncoding.EncodeToString(v), Type: TypeBase64}
+}
+
+func HexBinaryValue(name string, v []byte) ParameterValue {
+	return ParameterValue{Name: name, Value: hex.EncodeToString(v), Type: TypeHexBinary}
+}
+
+func (p ParameterValue) Int() (int64, error) {
+	return strconv.ParseInt(strings.TrimSpace(p.Value), 10, 64)
+}
+
+func (p ParameterValue) Uint() (uint64, error) {
+	return strconv.ParseUint(strings.TrimSpace(p.Value), 10, 64)
+}
+
+// Bool accepts the xsd:boolean lexical forms "true", "false", "1" and "0".
+func (p ParameterValue) Bool() (bool, error) {
+	switch strings.TrimSpace(p.Value) {
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
+	}
+
+	return false, &strconv.NumError{Func: "Bool", Num: p.Value, Err: strconv.ErrSyntax}
+}
+
+func (p ParameterValue) Time() (DateTime, error) {
+	return ParseDateTime(p.Value)
+}
+
+func (p ParameterValue) Base64() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(p.Value))
+}
+
+func (p ParameterValue) HexBinary() ([]byte, error) {
+	return hex.DecodeString(strings.TrimSpace(p.Value))
+}
+
+func (p ParameterValue) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	err := e.EncodeToken(start)
+	if err != nil {
+		return err
+	}
+
+	err = e.EncodeElement(p.Name, xml.StartElement{Name: xml.Name{Local: "Name"}})
+	if err != nil {
+		return err
+	}
+
+	t := p.Type
+	if t == "" {
+		t = TypeString
+	}
+
+	v := xml.StartElement{
+		Name: xml.Name{Local: "Value"},
+		Attr: []xml.Attr{
+			xml.Attr{
+				Name: xml.Name{
+					Space: xmlutil.XMLSpaceSchemaInstance,
+					Local: "type",
+				},
+				Value: "xsd:" + string(t),
+			},
+		},
+	}
+
+	err = e.EncodeElement(p.Value, v)
+	if err != nil {
+		return err
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+func (p *ParameterValue) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v struct {
+		Name  string
+		Value struct {
+			Type  string `xml:"type,attr"`
+			Value string `xml:",chardata"`
+		}
+	}
+
+	err := d.DecodeElement(&v, &start)
+	if err != nil {
+		return err
+	}
+
+	p.Name = v.Name
+	p.Value = v.Value.Value
+	p.Type = ValueType(v.Value.Type)
+
+	// The prefix bound to the XML Schema namespace is up to the sender.
+	if i := strings.LastIndex(v.Value.Type, ":"); i >= 0 {
+		p.Type = ValueType(v.Value.Type[i+1:])
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package cwmp
+
+import (
+	"encoding/xml"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeParameterValue(t *testing.T) {
+	v := &SetParameterValues{
+		ParameterList: []ParameterValue{
+			UintValue("Device.ManagementServer.PeriodicInformInterval", 300),
+			BoolValue("Device.ManagementServer.PeriodicInformEnable", true),
+			ParameterValue{Name: "Device.DeviceInfo.ProvisioningCode", Value: "abc"},
+		},
+		ParameterKey: "key1",
+	}
+
+	want := `<SetParameterValues xmlns="urn:dslforum-org:cwmp-1-0"><ParameterList xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="cwmp:ParameterValueStruct[3]"><ParameterValueStruct><Name>Device.ManagementServer.PeriodicInformInterval</Name><Value xmlns:_XMLSchema-instance="http://www.w3.org/2001/XMLSchema-instance" _XMLSchema-instance:type="xsd:unsignedInt">300</Value></ParameterValueStruct><ParameterValueStruct><Name>Device.ManagementServer.PeriodicInformEnable</Name><Value xmlns:_XMLSchema-instance="http://www.w3.org/2001/XMLSchema-instance" _XMLSchema-instance:type="xsd:boolean">true</Value></ParameterValueStruct><ParameterValueStruct><Name>Device.DeviceInfo.ProvisioningCode</Name><Value xmlns:_XMLSchema-instance="http://www.w3.org/2001/XMLSchema-instance" _XMLSchema-instance:type="xsd:string">abc</Value></ParameterValueStruct></ParameterList><ParameterKey>key1</ParameterKey></SetParameterValues>`
+
+	assertRoundTrip(t, v, want)
+}
+
+func TestDecodeParameterValueType(t *testing.T) {
+	input := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:cwmp="urn:dslforum-org:cwmp-1-0" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xs="http://www.w3.org/2001/XMLSchema"><soapenv:Body><cwmp:GetParameterValuesResponse><ParameterList><ParameterValueStruct><Name>Device.DeviceInfo.UpTime</Name><Value xsi:type="xs:unsignedInt">42</Value></ParameterValueStruct><ParameterValueStruct><Name>Device.DeviceInfo.Description</Name><Value>untyped</Value></ParameterValueStruct></ParameterList></cwmp:GetParameterValuesResponse></soapenv:Body></soapenv:Envelope>`
+
+	e, err := Decode(xml.NewDecoder(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	got, ok := e.Body.(*GetParameterValuesResponse)
+	if !ok {
+		t.Fatal("Body is not type GetParameterValuesResponse")
+	}
+
+	assertEqual(t, 2, len(got.ParameterList))
+	assertEqual(t, TypeUnsignedInt, got.ParameterList[0].Type)
+	assertEqual(t, "42", got.ParameterList[0].Value)
+	assertEqual(t, ValueType(""), got.ParameterList[1].Type)
+	assertEqual(t, "untyped", got.ParameterList[1].Value)
+}
+
+func TestDecodeInformParameterTypes(t *testing.T) {
+	f, err := os.Open("testdata/inform.xml")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	e, err := Decode(xml.NewDecoder(f))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	got := e.Body.(*Inform).ParameterList
+
+	assertEqual(t, TypeString, got[0].Type)
+	assertEqual(t, TypeBoolean, got[6].Type)
+
+	b, err := got[6].Bool()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertEqual(t, false, b)
+}
+
+func TestParameterValueAccessors(t *testing.T) {
+	i, err := IntValue("n", -5).Int()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertEqual(t, int64(-5), i)
+
+	u, err := UintValue("n", 7).Uint()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertEqual(t, uint64(7), u)
+
+	l := LongValue("n", -1<<40)
+	assertEqual(t, TypeLong, l.Type)
+
+	i, err = l.Int()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertEqual(t, int64(-1<<40), i)
+
+	ul := UnsignedLongValue("n", 1<<63)
+	assertEqual(t, TypeUnsignedLong, ul.Type)
+
+	u, err = ul.Uint()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertEqual(t, uint64(1<<63), u)
+
+	_, err = ParameterValue{Value: "-1"}.Uint()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	for _, s := range []string{"1", "true"} {
+		b, err := ParameterValue{Value: s}.Bool()
+		if err != nil || !b {
+			t.Fatalf("Expected (%s) to be true", s)
+		}
+	}
+
+	_, err = ParameterValue{Value: "yes"}.Bool()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	want := time.Date(2020, 01, 02, 20, 50, 49, 0, time.UTC)
+
+	v := TimeValue("t", want)
+	assertEqual(t, "2020-01-02T20:50:49Z", v.Value)
+
+	tm, err := v.Time()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertEqual(t, true, want.Equal(tm.Time))
+
+	v = Base64Value("b", []byte("hello"))
+	assertEqual(t, "aGVsbG8=", v.Value)
+
+	b, err := v.Base64()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertEqual(t, "hello", string(b))
+
+	v = HexBinaryValue("h", []byte{0xde, 0xad})
+	assertEqual(t, "dead", v.Value)
+
+	b, err = v.HexBinary()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	assertEqual(t, []byte{0xde, 0xad}, b)
+}
@@ -0,0 +1,143 @@
+package cwmp
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/scottlangendyk/go-cwmp/xmlutil"
+)
+
+// SupportedVersions lists the CWMP versions understood by this package, in
+// ascending order.
+var SupportedVersions = CWMPVersions{"1.0", "1.1", "1.2", "1.3", "1.4"}
+
+const namespacePrefix = "urn:dslforum-org:cwmp-"
+
+// Namespace returns the XML namespace used by CWMP version v.
+func Namespace(v string) (string, bool) {
+	for _, s := range SupportedVersions {
+		if s == v {
+			return namespacePrefix + strings.Replace(v, ".", "-", 1), true
+		}
+	}
+
+	return "", false
+}
+
+// Version returns the CWMP version that uses the XML namespace ns.
+func Version(ns string) (string, bool) {
+	if !strings.HasPrefix(ns, namespacePrefix) {
+		return "", false
+	}
+
+	v := strings.Replace(strings.TrimPrefix(ns, namespacePrefix), "-", ".", 1)
+
+	_, ok := Namespace(v)
+	if !ok {
+		return "", false
+	}
+
+	return v, true
+}
+
+// Negotiate returns the CWMP version to use for the session started by an
+// Inform with header h. That is the highest version listed by the CPE in
+// SupportedCWMPVersions that this package supports, or the version of the
+// namespace the Inform was sent in if the CPE didn't list any.
+func Negotiate(h *Header) string {
+	version, ok := Version(h.Namespace)
+	if !ok {
+		version = SupportedVersions[0]
+	}
+
+	if h.SupportedCWMPVersions == nil {
+		return version
+	}
+
+	best := ""
+
+	for _, v := range *h.SupportedCWMPVersions {
+		v = strings.TrimSpace(v)
+
+		_, ok := Namespace(v)
+		if ok && v > best {
+			best = v
+		}
+	}
+
+	if best == "" {
+		return version
+	}
+
+	return best
+}
+
+// NewEncoder returns an encoder that writes messages to w in namespace ns
+// rather than XMLSpace.
+func NewEncoder(w io.Writer, ns string) *xml.Encoder {
+	if ns == "" || ns == XMLSpace {
+		return xml.NewEncoder(w)
+	}
+
+	return xml.NewEncoder(xmlutil.NewTranslator(w, map[string]string{XMLSpace: ns}))
+}
+
+// namespaceReader maps the elements of every supported CWMP namespace onto
+// XMLSpace, which is what the message types are declared in, and remembers
+// the namespace the message actually used.
+type namespaceReader struct {
+	d         *xml.Decoder
+	namespace string
+}
+
+func (r *namespaceReader) translate(n xml.Name) xml.Name {
+	if n.Space == XMLSpace {
+		r.remember(n.Space)
+		return n
+	}
+
+	_, ok := Version(n.Space)
+	if !ok {
+		return n
+	}
+
+	r.remember(n.Space)
+	n.Space = XMLSpace
+
+	return n
+}
+
+func (r *namespaceReader) remember(ns string) {
+	if r.namespace == "" {
+		r.namespace = ns
+	}
+}
+
+func (r *namespaceReader) Token() (xml.Token, error) {
+	t, err := r.d.Token()
+	if err != nil {
+		return t, err
+	}
+
+	switch el := t.(type) {
+	case xml.StartElement:
+		start := xml.StartElement{Name: r.translate(el.Name)}
+
+		for _, attr := range el.Attr {
+			_, ok := Version(attr.Value)
+			if ok && (attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns") {
+				attr.Value = XMLSpace
+			}
+
+			attr.Name = r.translate(attr.Name)
+			start.Attr = append(start.Attr, attr)
+		}
+
+		return start, nil
+	case xml.EndElement:
+		return xml.EndElement{Name: r.translate(el.Name)}, nil
+	}
+
+	return t, nil
+}
@@ -0,0 +1,118 @@
+package cwmp
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/scottlangendyk/go-cwmp/soap"
+)
+
+func TestNamespace(t *testing.T) {
+	ns, ok := Namespace("1.2")
+	assertEqual(t, true, ok)
+	assertEqual(t, "urn:dslforum-org:cwmp-1-2", ns)
+
+	_, ok = Namespace("2.0")
+	assertEqual(t, false, ok)
+
+	v, ok := Version("urn:dslforum-org:cwmp-1-4")
+	assertEqual(t, true, ok)
+	assertEqual(t, "1.4", v)
+
+	_, ok = Version("urn:dslforum-org:cwmp-1-9")
+	assertEqual(t, false, ok)
+
+	_, ok = Version("urn:example")
+	assertEqual(t, false, ok)
+}
+
+func TestNegotiate(t *testing.T) {
+	assertEqual(t, "1.0", Negotiate(&Header{}))
+	assertEqual(t, "1.2", Negotiate(&Header{Namespace: "urn:dslforum-org:cwmp-1-2"}))
+
+	h := &Header{
+		Namespace:             "urn:dslforum-org:cwmp-1-0",
+		SupportedCWMPVersions: &CWMPVersions{"1.0", "1.3", "1.2"},
+	}
+
+	assertEqual(t, "1.3", Negotiate(h))
+
+	h.SupportedCWMPVersions = &CWMPVersions{"2.0"}
+
+	assertEqual(t, "1.0", Negotiate(h))
+}
+
+func TestDecodeNamespaces(t *testing.T) {
+	for _, v := range SupportedVersions {
+		ns, _ := Namespace(v)
+
+		input := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:cwmp="` + ns + `"><soapenv:Header><cwmp:ID soapenv:mustUnderstand="1">1</cwmp:ID></soapenv:Header><soapenv:Body><cwmp:Reboot><CommandKey>reboot</CommandKey></cwmp:Reboot></soapenv:Body></soapenv:Envelope>`
+
+		e, err := Decode(xml.NewDecoder(strings.NewReader(input)))
+		if err != nil {
+			t.Fatalf("%s: err: %v", v, err)
+		}
+
+		got, ok := e.Body.(*Reboot)
+		if !ok {
+			t.Fatalf("%s: Body is not type Reboot", v)
+		}
+
+		assertEqual(t, "reboot", got.CommandKey)
+
+		h := e.Header.(*Header)
+
+		assertEqual(t, "1", *h.ID)
+		assertEqual(t, ns, h.Namespace)
+	}
+}
+
+func TestDecodeDefaultNamespace(t *testing.T) {
+	input := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body><Reboot xmlns="urn:dslforum-org:cwmp-1-3"><CommandKey>reboot</CommandKey></Reboot></Body></Envelope>`
+
+	e, err := Decode(xml.NewDecoder(strings.NewReader(input)))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertEqual(t, "reboot", e.Body.(*Reboot).CommandKey)
+	assertEqual(t, "urn:dslforum-org:cwmp-1-3", e.Header.(*Header).Namespace)
+}
+
+func TestDecodeUnsupportedNamespace(t *testing.T) {
+	input := `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:cwmp="urn:dslforum-org:cwmp-2-0"><soapenv:Body><cwmp:Reboot><CommandKey>reboot</CommandKey></cwmp:Reboot></soapenv:Body></soapenv:Envelope>`
+
+	_, err := Decode(xml.NewDecoder(strings.NewReader(input)))
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+}
+
+func TestNewEncoder(t *testing.T) {
+	var b bytes.Buffer
+
+	id := "1"
+
+	env := &soap.Envelope{
+		Header: &Header{ID: &id},
+		Body:   &RebootResponse{},
+	}
+
+	err := NewEncoder(&b, "urn:dslforum-org:cwmp-1-4").Encode(env)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	want := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Header xmlns="http://schemas.xmlsoap.org/soap/envelope/"><ID xmlns="urn:dslforum-org:cwmp-1-4" xmlns:envelope="http://schemas.xmlsoap.org/soap/envelope/" envelope:mustUnderstand="1">1</ID></Header><Body xmlns="http://schemas.xmlsoap.org/soap/envelope/"><RebootResponse xmlns="urn:dslforum-org:cwmp-1-4"></RebootResponse></Body></Envelope>`
+
+	assertEqual(t, want, b.String())
+
+	e, err := Decode(xml.NewDecoder(&b))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	assertEqual(t, "urn:dslforum-org:cwmp-1-4", e.Header.(*Header).Namespace)
+}
@@ -89,11 +89,16 @@ func (env Envelope) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	}
 
 	if env.Header != nil {
-		h := &element{
+		var h interface{} = &element{
 			Contents: env.Header,
 			Name: "Header",
 		}
 
+		// A header that marshals itself writes its own Header element.
+		if _, ok := env.Header.(xml.Marshaler); ok {
+			h = env.Header
+		}
+
 		err = e.Encode(h)
 		if err != nil {
 			return err
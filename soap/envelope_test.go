@@ -97,3 +97,31 @@ func TestEncodeEnvelopeWithHeader(t *testing.T) {
 		t.Errorf("Got (%s) Expected (%s)", b.String(), expected)
 	}
 }
+
+type marshalingHeader struct{}
+
+func (h marshalingHeader) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement("entry", xml.StartElement{Name: xml.Name{Space: XMLSpaceEnvelope, Local: "Header"}})
+}
+
+func TestEncodeEnvelopeWithMarshalingHeader(t *testing.T) {
+	env := Envelope{
+		Body:   "test",
+		Header: marshalingHeader{},
+	}
+
+	var b bytes.Buffer
+
+	e := xml.NewEncoder(&b)
+
+	err := e.Encode(&env)
+	if err != nil {
+		t.Errorf("%s", err)
+	}
+
+	expected := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Header xmlns="http://schemas.xmlsoap.org/soap/envelope/">entry</Header><Body xmlns="http://schemas.xmlsoap.org/soap/envelope/"><string>test</string></Body></Envelope>`
+
+	if b.String() != expected {
+		t.Errorf("Got (%s) Expected (%s)", b.String(), expected)
+	}
+}
@@ -0,0 +1,265 @@
+package stun
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// Message types from RFC 3489.
+const (
+	BindingRequest       uint16 = 0x0001
+	BindingResponse      uint16 = 0x0101
+	BindingErrorResponse uint16 = 0x0111
+)
+
+// Attribute types from RFC 3489, XOR-MAPPED-ADDRESS from RFC 5389 and the
+// TR-111 attributes.
+const (
+	AttrMappedAddress            uint16 = 0x0001
+	AttrResponseAddress          uint16 = 0x0002
+	AttrChangeRequest            uint16 = 0x0003
+	AttrSourceAddress            uint16 = 0x0004
+	AttrChangedAddress           uint16 = 0x0005
+	AttrUsername                 uint16 = 0x0006
+	AttrMessageIntegrity         uint16 = 0x0008
+	AttrErrorCode                uint16 = 0x0009
+	AttrUnknownAttributes        uint16 = 0x000A
+	AttrReflectedFrom            uint16 = 0x000B
+	AttrXORMappedAddress         uint16 = 0x8020
+	AttrConnectionRequestBinding uint16 = 0xC001
+	AttrBindingChange            uint16 = 0xC002
+)
+
+// ConnectionRequestBinding is the value of a CONNECTION-REQUEST-BINDING
+// attribute.
+const ConnectionRequestBinding = "dslforum.org/TR-111 "
+
+// magicCookie starts the transaction ID of RFC 5389 clients.
+const magicCookie = 0x2112A442
+
+const headerSize = 20
+
+var ErrInvalidMessage = errors.New("stun: Invalid message")
+
+type Attribute struct {
+	Type  uint16
+	Value []byte
+}
+
+type Message struct {
+	Type          uint16
+	TransactionID [16]byte
+	Attributes    []Attribute
+}
+
+// Parse decodes a STUN message.
+func Parse(b []byte) (*Message, error) {
+	if len(b) < headerSize {
+		return nil, ErrInvalidMessage
+	}
+
+	length := int(binary.BigEndian.Uint16(b[2:4]))
+	if length%4 != 0 || len(b) < headerSize+length {
+		return nil, ErrInvalidMessage
+	}
+
+	m := &Message{
+		Type: binary.BigEndian.Uint16(b[0:2]),
+	}
+
+	copy(m.TransactionID[:], b[4:headerSize])
+
+	body := b[headerSize : headerSize+length]
+
+	for len(body) > 0 {
+		if len(body) < 4 {
+			return nil, ErrInvalidMessage
+		}
+
+		t := binary.BigEndian.Uint16(body[0:2])
+		l := int(binary.BigEndian.Uint16(body[2:4]))
+
+		padded := (l + 3) &^ 3
+		if len(body) < 4+padded {
+			return nil, ErrInvalidMessage
+		}
+
+		m.Attributes = append(m.Attributes, Attribute{
+			Type:  t,
+			Value: append([]byte(nil), body[4:4+l]...),
+		})
+
+		body = body[4+padded:]
+	}
+
+	return m, nil
+}
+
+// Marshal encodes the message.
+func (m *Message) Marshal() []byte {
+	b := make([]byte, headerSize)
+
+	binary.BigEndian.PutUint16(b[0:2], m.Type)
+	copy(b[4:headerSize], m.TransactionID[:])
+
+	for _, a := range m.Attributes {
+		var h [4]byte
+
+		binary.BigEndian.PutUint16(h[0:2], a.Type)
+		binary.BigEndian.PutUint16(h[2:4], uint16(len(a.Value)))
+
+		b = append(b, h[:]...)
+		b = append(b, a.Value...)
+
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+	}
+
+	binary.BigEndian.PutUint16(b[2:4], uint16(len(b)-headerSize))
+
+	return b
+}
+
+// Get returns the value of the first attribute of type t.
+func (m *Message) Get(t uint16) ([]byte, bool) {
+	for _, a := range m.Attributes {
+		if a.Type == t {
+			return a.Value, true
+		}
+	}
+
+	return nil, false
+}
+
+func (m *Message) Add(t uint16, v []byte) {
+	m.Attributes = append(m.Attributes, Attribute{Type: t, Value: v})
+}
+
+// isRFC5389 reports whether the transaction ID starts with the magic cookie.
+func (m *Message) isRFC5389() bool {
+	return binary.BigEndian.Uint32(m.TransactionID[0:4]) == magicCookie
+}
+
+// integrity computes the MESSAGE-INTEGRITY of the encoded message b up to the
+// attribute, which starts at offset. As in RFC 3489 the text is padded with
+// zeros to a multiple of 64 bytes.
+func integrity(b []byte, offset int, key string) []byte {
+	text := make([]byte, (offset+63)&^63)
+	copy(text, b[:offset])
+
+	h := hmac.New(sha1.New, []byte(key))
+	h.Write(text)
+
+	return h.Sum(nil)
+}
+
+// AddMessageIntegrity appends a MESSAGE-INTEGRITY attribute keyed with key.
+// It must be the last attribute added.
+func (m *Message) AddMessageIntegrity(key string) {
+	m.Add(AttrMessageIntegrity, make([]byte, sha1.Size))
+
+	b := m.Marshal()
+	offset := len(b) - 4 - sha1.Size
+
+	copy(m.Attributes[len(m.Attributes)-1].Value, integrity(b, offset, key))
+}
+
+// CheckMessageIntegrity reports whether the encoded message b has a
+// MESSAGE-INTEGRITY attribute keyed with key.
+func CheckMessageIntegrity(b []byte, key string) bool {
+	if len(b) < headerSize {
+		return false
+	}
+
+	length := int(binary.BigEndian.Uint16(b[2:4]))
+	if len(b) < headerSize+length {
+		return false
+	}
+
+	offset := headerSize
+
+	for offset+4 <= headerSize+length {
+		t := binary.BigEndian.Uint16(b[offset : offset+2])
+		l := int(binary.BigEndian.Uint16(b[offset+2 : offset+4]))
+
+		if t == AttrMessageIntegrity {
+			if l != sha1.Size || offset+4+l > len(b) {
+				return false
+			}
+
+			return hmac.Equal(b[offset+4:offset+4+l], integrity(b, offset, key))
+		}
+
+		offset += 4 + (l+3)&^3
+	}
+
+	return false
+}
+
+// Address encodes an IPv4 address attribute such as MAPPED-ADDRESS.
+func Address(addr *net.UDPAddr) []byte {
+	b := make([]byte, 8)
+
+	b[1] = 0x01
+	binary.BigEndian.PutUint16(b[2:4], uint16(addr.Port))
+	copy(b[4:8], addr.IP.To4())
+
+	return b
+}
+
+// ParseAddress decodes an IPv4 address attribute.
+func ParseAddress(b []byte) (*net.UDPAddr, error) {
+	if len(b) != 8 || b[1] != 0x01 {
+		return nil, fmt.Errorf("stun: Invalid address (%x)", b)
+	}
+
+	return &net.UDPAddr{
+		IP:   net.IPv4(b[4], b[5], b[6], b[7]),
+		Port: int(binary.BigEndian.Uint16(b[2:4])),
+	}, nil
+}
+
+// xorAddress encodes or decodes an RFC 5389 XOR-MAPPED-ADDRESS.
+func xorAddress(b []byte) []byte {
+	x := append([]byte(nil), b...)
+
+	var cookie [4]byte
+	binary.BigEndian.PutUint32(cookie[:], magicCookie)
+
+	for i := 0; i < 2; i++ {
+		x[2+i] ^= cookie[i]
+	}
+
+	for i := 0; i < 4; i++ {
+		x[4+i] ^= cookie[i]
+	}
+
+	return x
+}
+
+// UnknownAttributes encodes an UNKNOWN-ATTRIBUTES attribute. As in RFC 3489 an
+// odd number of types is padded by repeating one.
+func UnknownAttributes(types ...uint16) []byte {
+	if len(types)%2 != 0 {
+		types = append(types, types[len(types)-1])
+	}
+
+	b := make([]byte, 2*len(types))
+
+	for i, t := range types {
+		binary.BigEndian.PutUint16(b[2*i:], t)
+	}
+
+	return b
+}
+
+// ErrorCode encodes an ERROR-CODE attribute.
+func ErrorCode(code int, reason string) []byte {
+	b := []byte{0, 0, byte(code / 100), byte(code % 100)}
+	return append(b, reason...)
+}
@@ -0,0 +1,101 @@
+package stun
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	m := &Message{
+		Type:          BindingRequest,
+		TransactionID: [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	}
+
+	m.Add(AttrUsername, []byte("cpe"))
+	m.Add(AttrConnectionRequestBinding, []byte(ConnectionRequestBinding))
+	m.Add(AttrBindingChange, nil)
+
+	b := m.Marshal()
+
+	if len(b) != 20+8+24+4 {
+		t.Fatalf("Unexpected length (%d)", len(b))
+	}
+
+	got, err := Parse(b)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if got.Type != m.Type || got.TransactionID != m.TransactionID || len(got.Attributes) != 3 {
+		t.Fatalf("Unexpected message (%v)", got)
+	}
+
+	username, ok := got.Get(AttrUsername)
+	if !ok || string(username) != "cpe" {
+		t.Fatalf("Expected (cpe) got (%s)", username)
+	}
+
+	if _, ok := got.Get(AttrBindingChange); !ok {
+		t.Fatal("Expected BINDING-CHANGE")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, b := range [][]byte{
+		{0, 1, 0, 0},
+		append([]byte{0, 1, 0, 8}, make([]byte, 16)...),
+		append([]byte{0, 1, 0, 4}, append(make([]byte, 16), 0, 6, 0, 8)...),
+	} {
+		_, err := Parse(b)
+		if err != ErrInvalidMessage {
+			t.Fatalf("Expected (%v) got (%v) for (%x)", ErrInvalidMessage, err, b)
+		}
+	}
+}
+
+func TestMessageIntegrity(t *testing.T) {
+	m := &Message{Type: BindingRequest}
+	m.Add(AttrUsername, []byte("cpe"))
+	m.AddMessageIntegrity("secret")
+
+	b := m.Marshal()
+
+	if !CheckMessageIntegrity(b, "secret") {
+		t.Fatal("Expected valid MESSAGE-INTEGRITY")
+	}
+
+	if CheckMessageIntegrity(b, "wrong") {
+		t.Fatal("Unexpected valid MESSAGE-INTEGRITY")
+	}
+
+	b[len(b)-30] ^= 1
+
+	if CheckMessageIntegrity(b, "secret") {
+		t.Fatal("Unexpected valid MESSAGE-INTEGRITY for a changed message")
+	}
+}
+
+func TestAddress(t *testing.T) {
+	addr := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 7547}
+
+	b := Address(addr)
+
+	if !bytes.Equal(b, []byte{0, 1, 0x1d, 0x7b, 192, 0, 2, 1}) {
+		t.Fatalf("Unexpected address (%x)", b)
+	}
+
+	got, err := ParseAddress(b)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if !got.IP.Equal(addr.IP) || got.Port != addr.Port {
+		t.Fatalf("Expected (%s) got (%s)", addr, got)
+	}
+
+	got, err = ParseAddress(xorAddress(xorAddress(b)))
+	if err != nil || got.String() != addr.String() {
+		t.Fatalf("Expected (%s) got (%v) (%v)", addr, got, err)
+	}
+}
@@ -0,0 +1,240 @@
+package stun
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultAddr is the address ListenAndServe uses if Server.Addr is empty.
+const DefaultAddr = ":3478"
+
+// Binding is the NAT mapping a CPE reported with a TR-111 Binding Request.
+type Binding struct {
+	Username string
+	Addr     *net.UDPAddr
+
+	// Changed is set if the CPE sent a BINDING-CHANGE attribute because
+	// the mapping changed since its last request.
+	Changed bool
+
+	Updated time.Time
+}
+
+// Server is a STUN server that answers Binding Requests as in RFC 3489 and
+// learns the NAT mappings of CPEs from authenticated ones with a
+// CONNECTION-REQUEST-BINDING attribute, keyed by their USERNAME.
+//
+// A response is sent to the RESPONSE-ADDRESS of a request if it has one on
+// the host the request came from, which TR-111 CPEs use to discover how long
+// their binding lasts. If Password is set the request must also carry a
+// valid MESSAGE-INTEGRITY, otherwise the response goes to the sender. The
+// server has a single address, so it doesn't send CHANGED-ADDRESS and answers
+// a CHANGE-REQUEST to change the IP or port with a 420 error.
+type Server struct {
+	Addr string
+
+	// Password returns the STUN password of a username. Bindings are only
+	// learned from requests with a valid MESSAGE-INTEGRITY for the
+	// username, and if Password is nil none are learned at all: anyone
+	// could otherwise claim a device's USERNAME and have its connection
+	// requests sent to them.
+	Password func(username string) (string, bool)
+
+	// OnBinding is called with every binding learned.
+	OnBinding func(b Binding)
+
+	mu       sync.Mutex
+	conn     net.PacketConn
+	bindings map[string]Binding
+}
+
+// Binding returns the last binding learned for username.
+func (s *Server) Binding(username string) (Binding, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.bindings[username]
+
+	return b, ok
+}
+
+func (s *Server) ListenAndServe() error {
+	addr := s.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(conn)
+}
+
+// Serve answers the requests received on conn until it is closed.
+func (s *Server) Serve(conn net.PacketConn) error {
+	s.mu.Lock()
+	s.conn = conn
+	s.mu.Unlock()
+
+	defer conn.Close()
+
+	buf := make([]byte, 1500)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+
+		resp, to := s.handle(buf[:n], udpAddr, conn.LocalAddr())
+		if resp == nil {
+			continue
+		}
+
+		conn.WriteTo(resp.Marshal(), to)
+	}
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+
+	return s.conn.Close()
+}
+
+// Flags of a CHANGE-REQUEST attribute.
+const (
+	changeIP   = 0x04
+	changePort = 0x02
+)
+
+func errorResponse(req *Message, code int, reason string) *Message {
+	resp := &Message{
+		Type:          BindingErrorResponse,
+		TransactionID: req.TransactionID,
+	}
+
+	resp.Add(AttrErrorCode, ErrorCode(code, reason))
+
+	return resp
+}
+
+// handle answers a request from the CPE at from, returning the response and
+// the address to send it to.
+func (s *Server) handle(b []byte, from *net.UDPAddr, local net.Addr) (*Message, *net.UDPAddr) {
+	req, err := Parse(b)
+	if err != nil || req.Type != BindingRequest || from.IP.To4() == nil {
+		return nil, nil
+	}
+
+	if v, ok := req.Get(AttrChangeRequest); ok {
+		if len(v) != 4 {
+			return errorResponse(req, 400, "Bad Request"), from
+		}
+
+		if v[3]&(changeIP|changePort) != 0 {
+			resp := errorResponse(req, 420, "Unknown Attribute")
+			resp.Add(AttrUnknownAttributes, UnknownAttributes(AttrChangeRequest))
+
+			return resp, from
+		}
+	}
+
+	username, _ := req.Get(AttrUsername)
+
+	to := from
+
+	if v, ok := req.Get(AttrResponseAddress); ok {
+		addr, err := ParseAddress(v)
+		if err != nil {
+			return errorResponse(req, 400, "Bad Request"), from
+		}
+
+		// Responses are only sent elsewhere on the host the request came
+		// from, all TR-111 needs to find how long its binding lasts, so the
+		// server can't be used to reflect traffic at others (RFC 3489
+		// section 12.1).
+		if addr.IP.Equal(from.IP) && s.authenticated(b, string(username)) {
+			to = addr
+		}
+	}
+
+	if _, ok := req.Get(AttrConnectionRequestBinding); ok && !s.authenticated(b, string(username)) {
+		return errorResponse(req, 401, "Unauthorized"), from
+	}
+
+	if _, ok := req.Get(AttrConnectionRequestBinding); ok && len(username) > 0 && s.Password != nil {
+		_, changed := req.Get(AttrBindingChange)
+
+		s.learn(Binding{
+			Username: string(username),
+			Addr:     from,
+			Changed:  changed,
+			Updated:  time.Now(),
+		})
+	}
+
+	resp := &Message{
+		Type:          BindingResponse,
+		TransactionID: req.TransactionID,
+	}
+
+	mapped := Address(from)
+
+	resp.Add(AttrMappedAddress, mapped)
+
+	if l, ok := local.(*net.UDPAddr); ok && l.IP.To4() != nil {
+		resp.Add(AttrSourceAddress, Address(l))
+	}
+
+	if to != from {
+		resp.Add(AttrReflectedFrom, mapped)
+	}
+
+	if req.isRFC5389() {
+		resp.Add(AttrXORMappedAddress, xorAddress(mapped))
+	}
+
+	return resp, to
+}
+
+// authenticated reports whether the request b has a valid MESSAGE-INTEGRITY
+// for username, or Password isn't set.
+func (s *Server) authenticated(b []byte, username string) bool {
+	if s.Password == nil {
+		return true
+	}
+
+	password, ok := s.Password(username)
+
+	return ok && CheckMessageIntegrity(b, password)
+}
+
+func (s *Server) learn(b Binding) {
+	s.mu.Lock()
+
+	if s.bindings == nil {
+		s.bindings = make(map[string]Binding)
+	}
+
+	s.bindings[b.Username] = b
+
+	s.mu.Unlock()
+
+	if s.OnBinding != nil {
+		s.OnBinding(b)
+	}
+}
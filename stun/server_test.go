@@ -0,0 +1,320 @@
+package stun
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T, s *Server) net.Addr {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	go s.Serve(conn)
+
+	return conn.LocalAddr()
+}
+
+func exchange(t *testing.T, addr net.Addr, req *Message) (*Message, *net.UDPAddr) {
+	conn, err := net.DialUDP("udp", nil, addr.(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	defer conn.Close()
+
+	_, err = conn.Write(req.Marshal())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 1500)
+
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err := Parse(buf[:n])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if resp.TransactionID != req.TransactionID {
+		t.Fatalf("Expected transaction (%x) got (%x)", req.TransactionID, resp.TransactionID)
+	}
+
+	return resp, conn.LocalAddr().(*net.UDPAddr)
+}
+
+func bindingRequest(username string) *Message {
+	m := &Message{
+		Type:          BindingRequest,
+		TransactionID: [16]byte{15: 1},
+	}
+
+	m.Add(AttrUsername, []byte(username))
+	m.Add(AttrConnectionRequestBinding, []byte(ConnectionRequestBinding))
+
+	return m
+}
+
+func TestServerBinding(t *testing.T) {
+	learned := make(chan Binding, 1)
+
+	s := &Server{
+		Password: func(username string) (string, bool) {
+			return "secret", username == "cpe"
+		},
+		OnBinding: func(b Binding) {
+			learned <- b
+		},
+	}
+	defer s.Close()
+
+	req := bindingRequest("cpe")
+	req.Add(AttrBindingChange, nil)
+	req.AddMessageIntegrity("secret")
+
+	resp, local := exchange(t, listen(t, s), req)
+
+	if resp.Type != BindingResponse {
+		t.Fatalf("Expected (%x) got (%x)", BindingResponse, resp.Type)
+	}
+
+	v, _ := resp.Get(AttrMappedAddress)
+
+	mapped, err := ParseAddress(v)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if mapped.String() != local.String() {
+		t.Fatalf("Expected (%s) got (%s)", local, mapped)
+	}
+
+	b := <-learned
+	if b.Username != "cpe" || b.Addr.String() != local.String() || !b.Changed {
+		t.Fatalf("Unexpected binding (%v)", b)
+	}
+
+	got, ok := s.Binding("cpe")
+	if !ok || got.Addr.String() != local.String() {
+		t.Fatalf("Unexpected binding (%v)", got)
+	}
+}
+
+func TestServerPlainBinding(t *testing.T) {
+	s := &Server{}
+	defer s.Close()
+
+	req := &Message{Type: BindingRequest}
+	binary.BigEndian.PutUint32(req.TransactionID[0:4], magicCookie)
+
+	resp, local := exchange(t, listen(t, s), req)
+
+	v, ok := resp.Get(AttrXORMappedAddress)
+	if !ok {
+		t.Fatal("Expected XOR-MAPPED-ADDRESS")
+	}
+
+	mapped, err := ParseAddress(xorAddress(v))
+	if err != nil || mapped.String() != local.String() {
+		t.Fatalf("Expected (%s) got (%v) (%v)", local, mapped, err)
+	}
+
+	if _, ok := s.Binding(""); ok {
+		t.Fatal("Unexpected binding")
+	}
+}
+
+func TestServerNoPassword(t *testing.T) {
+	s := &Server{}
+	defer s.Close()
+
+	resp, _ := exchange(t, listen(t, s), bindingRequest("cpe"))
+
+	if resp.Type != BindingResponse {
+		t.Fatalf("Expected (%x) got (%x)", BindingResponse, resp.Type)
+	}
+
+	if _, ok := s.Binding("cpe"); ok {
+		t.Fatal("Unexpected binding")
+	}
+}
+
+func TestServerMessageIntegrity(t *testing.T) {
+	s := &Server{
+		Password: func(username string) (string, bool) {
+			return "secret", username == "cpe"
+		},
+	}
+	defer s.Close()
+
+	addr := listen(t, s)
+
+	resp, _ := exchange(t, addr, bindingRequest("cpe"))
+
+	v, _ := resp.Get(AttrErrorCode)
+	if resp.Type != BindingErrorResponse || len(v) < 4 || v[2] != 4 || v[3] != 1 {
+		t.Fatalf("Expected 401 error got (%x) (%x)", resp.Type, v)
+	}
+
+	if _, ok := s.Binding("cpe"); ok {
+		t.Fatal("Unexpected binding")
+	}
+
+	req := bindingRequest("cpe")
+	req.AddMessageIntegrity("secret")
+
+	resp, _ = exchange(t, addr, req)
+
+	if resp.Type != BindingResponse {
+		t.Fatalf("Expected (%x) got (%x)", BindingResponse, resp.Type)
+	}
+
+	if _, ok := s.Binding("cpe"); !ok {
+		t.Fatal("Expected binding")
+	}
+}
+
+func TestServerResponseAddress(t *testing.T) {
+	s := &Server{}
+	defer s.Close()
+
+	addr := listen(t, s)
+
+	other, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	defer other.Close()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	defer conn.Close()
+
+	req := bindingRequest("cpe")
+	req.Add(AttrResponseAddress, Address(other.LocalAddr().(*net.UDPAddr)))
+
+	_, err = conn.WriteTo(req.Marshal(), addr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	other.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	buf := make([]byte, 1500)
+
+	n, _, err := other.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	resp, err := Parse(buf[:n])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, attr := range []uint16{AttrMappedAddress, AttrReflectedFrom} {
+		v, _ := resp.Get(attr)
+
+		got, err := ParseAddress(v)
+		if err != nil || got.String() != conn.LocalAddr().String() {
+			t.Fatalf("Expected (%s) for (%x) got (%v) (%v)", conn.LocalAddr(), attr, got, err)
+		}
+	}
+
+	if _, ok := resp.Get(AttrChangedAddress); ok {
+		t.Fatal("Unexpected CHANGED-ADDRESS")
+	}
+}
+
+func TestServerForeignResponseAddress(t *testing.T) {
+	s := &Server{}
+	defer s.Close()
+
+	req := bindingRequest("cpe")
+	req.Add(AttrResponseAddress, Address(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 9}))
+
+	// Answered to the sender rather than the other host.
+	resp, local := exchange(t, listen(t, s), req)
+
+	v, _ := resp.Get(AttrMappedAddress)
+
+	mapped, err := ParseAddress(v)
+	if err != nil || mapped.String() != local.String() {
+		t.Fatalf("Expected (%s) got (%v) (%v)", local, mapped, err)
+	}
+
+	if _, ok := resp.Get(AttrReflectedFrom); ok {
+		t.Fatal("Unexpected REFLECTED-FROM")
+	}
+}
+
+func TestServerResponseAddressIntegrity(t *testing.T) {
+	s := &Server{
+		Password: func(username string) (string, bool) {
+			return "secret", username == "cpe"
+		},
+	}
+	defer s.Close()
+
+	other, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	defer other.Close()
+
+	// Without MESSAGE-INTEGRITY the response goes back to the sender.
+	req := &Message{Type: BindingRequest, TransactionID: [16]byte{15: 1}}
+	req.Add(AttrUsername, []byte("cpe"))
+	req.Add(AttrResponseAddress, Address(other.LocalAddr().(*net.UDPAddr)))
+
+	resp, _ := exchange(t, listen(t, s), req)
+
+	if _, ok := resp.Get(AttrReflectedFrom); ok {
+		t.Fatal("Unexpected REFLECTED-FROM")
+	}
+}
+
+func TestServerChangeRequest(t *testing.T) {
+	s := &Server{}
+	defer s.Close()
+
+	addr := listen(t, s)
+
+	req := bindingRequest("cpe")
+	req.Add(AttrChangeRequest, []byte{0, 0, 0, changeIP | changePort})
+
+	resp, _ := exchange(t, addr, req)
+
+	v, _ := resp.Get(AttrErrorCode)
+	if resp.Type != BindingErrorResponse || len(v) < 4 || v[2] != 4 || v[3] != 20 {
+		t.Fatalf("Expected 420 error got (%x) (%x)", resp.Type, v)
+	}
+
+	v, _ = resp.Get(AttrUnknownAttributes)
+	if len(v) != 4 || binary.BigEndian.Uint16(v) != AttrChangeRequest {
+		t.Fatalf("Unexpected UNKNOWN-ATTRIBUTES (%x)", v)
+	}
+
+	req = bindingRequest("cpe")
+	req.Add(AttrChangeRequest, []byte{0, 0, 0, 0})
+
+	resp, _ = exchange(t, addr, req)
+
+	if resp.Type != BindingResponse {
+		t.Fatalf("Expected (%x) got (%x)", BindingResponse, resp.Type)
+	}
+}
@@ -1,13 +1,27 @@
 package xmlutil
 
 import (
-	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 )
 
+const (
+	XMLSpaceSchema         = "http://www.w3.org/2001/XMLSchema"
+	XMLSpaceSchemaInstance = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// wellKnownPrefixes are used for namespaces missing from the prefix map so
+// that xsi:type attributes come out in the form CPEs expect. Namespaced
+// attributes with an xsd: value, such as xsi:type and SOAP-ENC:arrayType, get
+// the xsd prefix declared if it isn't already.
+var wellKnownPrefixes = map[string]string{
+	XMLSpaceSchema:         "xsd",
+	XMLSpaceSchemaInstance: "xsi",
+}
+
 type Prefixer interface {
 	io.Writer
 }
@@ -27,7 +41,7 @@ type stackItem struct {
 type prefixer struct {
 	p     map[string]string
 	stack []*stackItem
-	b     bytes.Buffer
+	b     tokenBuffer
 	e     *xml.Encoder
 }
 
@@ -43,6 +57,15 @@ func (p *prefixer) prefixForNamespace(ns string) string {
 	return ""
 }
 
+func (p *prefixer) declare(prefix, space string) xml.Attr {
+	return xml.Attr{
+		Name: xml.Name{
+			Local: fmt.Sprintf("xmlns:%s", prefix),
+		},
+		Value: space,
+	}
+}
+
 func (p *prefixer) push(start xml.StartElement) xml.StartElement {
 	item := &stackItem{
 		Start: &xml.StartElement{
@@ -66,14 +89,8 @@ func (p *prefixer) push(start xml.StartElement) xml.StartElement {
 
 		for _, space := range spaces {
 			prefix := p.p[space]
-			attr := xml.Attr{
-				Name: xml.Name{
-					Local: fmt.Sprintf("xmlns:%s", prefix),
-				},
-				Value: space,
-			}
 
-			attrs = append(attrs, attr)
+			attrs = append(attrs, p.declare(prefix, space))
 			item.Prefixes[space] = prefix
 		}
 	}
@@ -96,6 +113,12 @@ func (p *prefixer) push(start xml.StartElement) xml.StartElement {
 		if attr.Name.Space == "xmlns" {
 			pfx := p.prefixForNamespace(attr.Value)
 
+			if pfx == "" && wellKnownPrefixes[attr.Value] != "" {
+				pfx = wellKnownPrefixes[attr.Value]
+				item.Prefixes[attr.Value] = pfx
+				attrs = append(attrs, p.declare(pfx, attr.Value))
+			}
+
 			if pfx == "" {
 				item.Prefixes[attr.Value] = attr.Name.Local
 				attr.Name.Space = ""
@@ -120,6 +143,11 @@ func (p *prefixer) push(start xml.StartElement) xml.StartElement {
 			attr.Name.Local = fmt.Sprintf("%s:%s", pfx, attr.Name.Local)
 		}
 
+		if pfx != "" && strings.HasPrefix(attr.Value, "xsd:") && p.prefixForNamespace(XMLSpaceSchema) == "" {
+			item.Prefixes[XMLSpaceSchema] = "xsd"
+			item.Start.Attr = append(item.Start.Attr, p.declare("xsd", XMLSpaceSchema))
+		}
+
 		item.Start.Attr = append(item.Start.Attr, attr)
 	}
 
@@ -142,20 +170,7 @@ func (p *prefixer) pop() xml.EndElement {
 }
 
 func (p *prefixer) Write(w []byte) (int, error) {
-	d := xml.NewDecoder(&p.b)
-
-	n, _ := p.b.Write(w)
-
-	for {
-		t, err := d.RawToken()
-		if err == io.EOF {
-			return n, nil
-		}
-
-		if err != nil {
-			return n, err
-		}
-
+	err := p.b.write(w, func(t xml.Token) error {
 		switch el := t.(type) {
 		case xml.StartElement:
 			t = p.push(el)
@@ -163,14 +178,16 @@ func (p *prefixer) Write(w []byte) (int, error) {
 			t = p.pop()
 		}
 
-		err = p.e.EncodeToken(t)
+		err := p.e.EncodeToken(t)
 		if err != nil {
-			return n, err
+			return err
 		}
 
-		err = p.e.Flush()
-		if err != nil {
-			return n, err
-		}
+		return p.e.Flush()
+	})
+	if err != nil {
+		return 0, err
 	}
+
+	return len(w), nil
 }
@@ -211,3 +211,69 @@ func TestPrefixerMultiWrite(t *testing.T) {
 		t.Fatalf("Doesn't match\nwant: %s\ngot:  %s", want, got)
 	}
 }
+
+func TestPrefixerSchemaInstance(t *testing.T) {
+	var b bytes.Buffer
+
+	p := NewPrefixer(&b, nil)
+
+	input := `<ParameterValueStruct><Name>Device.DeviceInfo.UpTime</Name><Value xmlns:_XMLSchema-instance="http://www.w3.org/2001/XMLSchema-instance" _XMLSchema-instance:type="xsd:unsignedInt">42</Value></ParameterValueStruct>`
+
+	_, err := fmt.Fprint(p, input)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	want := `<ParameterValueStruct><Name>Device.DeviceInfo.UpTime</Name><Value xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xsi:type="xsd:unsignedInt">42</Value></ParameterValueStruct>`
+	got := b.String()
+
+	if want != got {
+		t.Fatalf("Doesn't match\nwant: %s\ngot:  %s", want, got)
+	}
+}
+
+func TestPrefixerSchemaInstanceMapped(t *testing.T) {
+	var b bytes.Buffer
+
+	p := NewPrefixer(&b, map[string]string{
+		"urn:dslforum-org:cwmp-1-0": "cwmp",
+		XMLSpaceSchema:              "xsd",
+		XMLSpaceSchemaInstance:      "xsi",
+	})
+
+	input := `<Inform xmlns="urn:dslforum-org:cwmp-1-0"><Value xmlns:_XMLSchema-instance="http://www.w3.org/2001/XMLSchema-instance" _XMLSchema-instance:type="xsd:boolean">1</Value></Inform>`
+
+	_, err := fmt.Fprint(p, input)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	want := `<cwmp:Inform xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:cwmp="urn:dslforum-org:cwmp-1-0"><Value xsi:type="xsd:boolean">1</Value></cwmp:Inform>`
+	got := b.String()
+
+	if want != got {
+		t.Fatalf("Doesn't match\nwant: %s\ngot:  %s", want, got)
+	}
+}
+
+func TestPrefixerArrayType(t *testing.T) {
+	var b bytes.Buffer
+
+	p := NewPrefixer(&b, map[string]string{
+		"http://schemas.xmlsoap.org/soap/encoding/": "soapenc",
+	})
+
+	input := `<MethodList xmlns:encoding="http://schemas.xmlsoap.org/soap/encoding/" encoding:arrayType="xsd:string[1]"><string>Inform</string></MethodList>`
+
+	_, err := fmt.Fprint(p, input)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	want := `<MethodList xmlns:soapenc="http://schemas.xmlsoap.org/soap/encoding/" xmlns:xsd="http://www.w3.org/2001/XMLSchema" soapenc:arrayType="xsd:string[1]"><string>Inform</string></MethodList>`
+	got := b.String()
+
+	if want != got {
+		t.Fatalf("Doesn't match\nwant: %s\ngot:  %s", want, got)
+	}
+}
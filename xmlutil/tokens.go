@@ -0,0 +1,55 @@
+package xmlutil
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+// tokenBuffer turns the bytes written by an xml.Encoder back into raw tokens.
+// The encoder flushes whenever its buffer fills, so a write may end part way
+// through a token; that part is held back until the rest arrives.
+type tokenBuffer struct {
+	b []byte
+}
+
+func incomplete(err error) bool {
+	se, ok := err.(*xml.SyntaxError)
+
+	return ok && se.Msg == "unexpected EOF"
+}
+
+func (t *tokenBuffer) write(w []byte, fn func(xml.Token) error) error {
+	t.b = append(t.b, w...)
+
+	for {
+		d := xml.NewDecoder(bytes.NewReader(t.b))
+
+		tok, err := d.RawToken()
+		if err == io.EOF || (err != nil && incomplete(err)) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		raw := t.b[:d.InputOffset()]
+		tok = xml.CopyToken(tok)
+		t.b = t.b[d.InputOffset():]
+
+		err = fn(tok)
+		if err != nil {
+			return err
+		}
+
+		// A fresh decoder is used for every token, so the end of an empty
+		// element has to be generated here.
+		if start, ok := tok.(xml.StartElement); ok && bytes.HasSuffix(raw, []byte("/>")) {
+			err = fn(start.End())
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package xmlutil
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// NewTranslator returns a writer that passes XML through to w with every
+// namespace declaration found in spaces replaced by the namespace it maps to.
+func NewTranslator(w io.Writer, spaces map[string]string) io.Writer {
+	return &translator{
+		e:      xml.NewEncoder(w),
+		spaces: spaces,
+	}
+}
+
+type translator struct {
+	e      *xml.Encoder
+	spaces map[string]string
+	b      tokenBuffer
+}
+
+// flatten keeps a raw name as it was written, prefix and all, so the encoder
+// doesn't try to declare the prefix as a namespace of its own.
+func flatten(n xml.Name) xml.Name {
+	if n.Space == "" {
+		return n
+	}
+
+	return xml.Name{Local: fmt.Sprintf("%s:%s", n.Space, n.Local)}
+}
+
+func (t *translator) translate(el xml.StartElement) xml.StartElement {
+	start := xml.StartElement{Name: flatten(el.Name)}
+
+	for _, attr := range el.Attr {
+		isDecl := attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns")
+
+		if space, ok := t.spaces[attr.Value]; ok && isDecl {
+			attr.Value = space
+		}
+
+		attr.Name = flatten(attr.Name)
+		start.Attr = append(start.Attr, attr)
+	}
+
+	return start
+}
+
+func (t *translator) Write(w []byte) (int, error) {
+	err := t.b.write(w, func(tok xml.Token) error {
+		switch el := tok.(type) {
+		case xml.StartElement:
+			tok = t.translate(el)
+		case xml.EndElement:
+			tok = xml.EndElement{Name: flatten(el.Name)}
+		}
+
+		err := t.e.EncodeToken(tok)
+		if err != nil {
+			return err
+		}
+
+		return t.e.Flush()
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(w), nil
+}
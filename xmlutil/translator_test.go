@@ -0,0 +1,73 @@
+package xmlutil
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestTranslator(t *testing.T) {
+	var b bytes.Buffer
+
+	tr := NewTranslator(&b, map[string]string{
+		"urn:dslforum-org:cwmp-1-0": "urn:dslforum-org:cwmp-1-2",
+	})
+
+	input := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body xmlns="http://schemas.xmlsoap.org/soap/envelope/"><InformResponse xmlns="urn:dslforum-org:cwmp-1-0" xmlns:cwmp="urn:dslforum-org:cwmp-1-0"><MaxEnvelopes cwmp:attr="urn:dslforum-org:cwmp-1-0">1</MaxEnvelopes></InformResponse></Body></Envelope>`
+
+	_, err := fmt.Fprint(tr, input)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	want := `<Envelope xmlns="http://schemas.xmlsoap.org/soap/envelope/"><Body xmlns="http://schemas.xmlsoap.org/soap/envelope/"><InformResponse xmlns="urn:dslforum-org:cwmp-1-2" xmlns:cwmp="urn:dslforum-org:cwmp-1-2"><MaxEnvelopes cwmp:attr="urn:dslforum-org:cwmp-1-0">1</MaxEnvelopes></InformResponse></Body></Envelope>`
+	got := b.String()
+
+	if want != got {
+		t.Fatalf("Doesn't match\nwant: %s\ngot:  %s", want, got)
+	}
+}
+
+func TestTranslatorSplitWrites(t *testing.T) {
+	var b bytes.Buffer
+
+	tr := NewTranslator(&b, map[string]string{"a": "b"})
+
+	input := `<test xmlns="a"><empty/><text>one &amp; two</text></test>`
+
+	for i := 0; i < len(input); i++ {
+		_, err := fmt.Fprint(tr, input[i:i+1])
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	want := `<test xmlns="b"><empty></empty><text>one &amp; two</text></test>`
+	got := b.String()
+
+	if want != got {
+		t.Fatalf("Doesn't match\nwant: %s\ngot:  %s", want, got)
+	}
+}
+
+func TestPrefixerSplitWrites(t *testing.T) {
+	var b bytes.Buffer
+
+	p := NewPrefixer(&b, map[string]string{"mynamespace": "yo"})
+
+	input := `<test xmlns="mynamespace"><one attr="value">Hey</one></test>`
+
+	for i := 0; i < len(input); i++ {
+		_, err := fmt.Fprint(p, input[i:i+1])
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	want := `<yo:test xmlns:yo="mynamespace"><one attr="value">Hey</one></yo:test>`
+	got := b.String()
+
+	if want != got {
+		t.Fatalf("Doesn't match\nwant: %s\ngot:  %s", want, got)
+	}
+}